@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/slack-go/slack"
+
+	"slack-rag-server/src/handlers"
+	"slack-rag-server/src/services"
+	"slack-rag-server/src/types"
+	"slack-rag-server/src/utils"
+)
+
+// attachFlag collects the files given with repeated -attach flags
+type attachFlag []string
+
+func (a *attachFlag) String() string {
+	return strings.Join(*a, ",")
+}
+
+func (a *attachFlag) Set(path string) error {
+	*a = append(*a, path)
+	return nil
+}
+
+// chatSession is a conversation with the agent in the terminal
+type chatSession struct {
+	bedrockService *services.BedrockService
+	out            io.Writer
+	sessionID      string
+	aliasID        string
+	traceback      bool
+	slackFormat    bool
+	attachments    []string
+}
+
+// runChat talks to the agent from the terminal through the same answering path, message
+// flags and rendering as Slack, to debug prompts and reproduce user issues
+func runChat(args []string) {
+	flags := flag.NewFlagSet("chat", flag.ExitOnError)
+	sessionID := flags.String("session", "", "Agent session ID to continue (default: a new session)")
+	thread := flags.String("thread", "", "Continue the agent session of a Slack thread, given as <channel>:<timestamp> (reads RAGBOT_SESSIONS_FILE)")
+	traceback := flags.Bool("traceback", false, "Show the agent traceback after every answer")
+	slackFormat := flags.Bool("slack", false, "Show answers as Slack renders them instead of streaming markdown")
+	verbose := flags.Bool("verbose", false, "Show the server log on stderr")
+	var attachments attachFlag
+	flags.Var(&attachments, "attach", "File to attach to the first question (repeatable)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: slack-rag-server chat [flags] [question]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if err := godotenv.Load(); err != nil && *verbose {
+		log.Println("Warning: Error loading .env file:", err)
+	}
+
+	// Keep the terminal for the conversation: the server log goes to stderr with -verbose
+	// and is dropped otherwise
+	out := os.Stdout
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	bedrockService, err := services.NewBedrockService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize Bedrock service: %v\n", err)
+		os.Exit(1)
+	}
+
+	chat := &chatSession{
+		bedrockService: bedrockService,
+		out:            out,
+		sessionID:      *sessionID,
+		traceback:      *traceback,
+		slackFormat:    *slackFormat,
+		attachments:    attachments,
+	}
+	if *thread != "" {
+		sessions, err := services.NewSessionStore()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load sessions: %v\n", err)
+			os.Exit(1)
+		}
+		chat.sessionID = sessions.SessionID(*thread)
+		chat.aliasID = sessions.AliasID(*thread)
+	}
+	if chat.sessionID == "" {
+		chat.sessionID = newChatSessionID()
+	}
+
+	// Answer a single question given on the command line
+	if flags.NArg() > 0 {
+		if !chat.ask(strings.Join(flags.Args(), " ")) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(out, "Chatting with the %s backend in session %s. Type /help for commands.\n", bedrockService.BackendName(), chat.sessionID)
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Fprint(out, "\n> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "/"):
+			if !chat.command(line) {
+				return
+			}
+		default:
+			chat.ask(line)
+		}
+	}
+}
+
+// newChatSessionID returns a new agent session ID for the terminal
+func newChatSessionID() string {
+	return fmt.Sprintf("chat-%d", time.Now().UnixNano())
+}
+
+// command runs a chat command, returning false to end the chat
+func (c *chatSession) command(line string) bool {
+	name, argument, _ := strings.Cut(line, " ")
+	argument = strings.TrimSpace(argument)
+
+	switch name {
+	case "/quit", "/exit":
+		return false
+	case "/attach":
+		if argument == "" {
+			fmt.Fprintln(c.out, "Usage: /attach <path>")
+			break
+		}
+		c.attachments = append(c.attachments, argument)
+		fmt.Fprintf(c.out, "%s will be attached to the next question.\n", argument)
+	case "/session":
+		if argument != "" {
+			c.sessionID = argument
+			c.aliasID = ""
+		}
+		fmt.Fprintf(c.out, "Session: %s\n", c.sessionID)
+	case "/new":
+		c.newSession()
+	case "/traceback":
+		c.traceback = !c.traceback
+		fmt.Fprintf(c.out, "Traceback after every answer: %t\n", c.traceback)
+	default:
+		fmt.Fprintln(c.out, "Ask a question, optionally with --traceback, --agent=<name>, --new-session or --retrieve-only.")
+		fmt.Fprintln(c.out, "/attach <path>    Attach a file to the next question")
+		fmt.Fprintln(c.out, "/session [id]     Show the session ID, or continue another session")
+		fmt.Fprintln(c.out, "/new              End the session and start a new one")
+		fmt.Fprintln(c.out, "/traceback        Toggle the traceback after every answer")
+		fmt.Fprintln(c.out, "/quit             Leave the chat")
+	}
+	return true
+}
+
+// newSession ends the current agent session and starts a new one
+func (c *chatSession) newSession() {
+	if err := c.bedrockService.EndSession(c.sessionID, c.aliasID); err != nil {
+		fmt.Fprintf(c.out, "Warning: could not end session %s: %v\n", c.sessionID, err)
+	}
+	c.sessionID = newChatSessionID()
+	c.aliasID = ""
+	fmt.Fprintf(c.out, "Started session %s.\n", c.sessionID)
+}
+
+// ask parses the message flags in a question, sends it to the agent and prints the answer,
+// returning whether the question was answered
+func (c *chatSession) ask(text string) bool {
+	message := utils.ParseMessage(text)
+
+	if message.NewSession {
+		c.newSession()
+		if message.Text == "" {
+			return true
+		}
+	}
+
+	// Continue on the alias the session was started on unless another agent is asked for
+	sessionContext := types.SessionContext{AgentAliasID: c.aliasID}
+	if message.Agent != "" {
+		aliasID, ok := c.bedrockService.ResolveAgentAlias(message.Agent)
+		if !ok {
+			fmt.Fprintf(c.out, "Unknown agent %q. Available agents: %s\n", message.Agent, strings.Join(c.bedrockService.AgentAliasNames(), ", "))
+			return false
+		}
+		sessionContext.AgentAliasID = aliasID
+	}
+
+	if message.RetrieveOnly {
+		return c.retrieve(message.Text)
+	}
+	c.aliasID = sessionContext.AgentAliasID
+
+	attachments, err := loadAttachments(c.attachments)
+	if err != nil {
+		fmt.Fprintf(c.out, "Error: %v\n", err)
+		return false
+	}
+	c.attachments = nil
+
+	// Print the answer as it streams in, unless it is shown the way Slack renders it
+	streamed := false
+	if !c.slackFormat {
+		sessionContext.OnChunk = func(chunk string) {
+			streamed = true
+			fmt.Fprint(c.out, chunk)
+		}
+	}
+
+	start := time.Now()
+	response, err := c.bedrockService.Answer(message.Text, c.sessionID, attachments, c.traceback || message.Traceback, sessionContext)
+	if err != nil {
+		fmt.Fprintf(c.out, "Error invoking Bedrock agent: %v\n", err)
+		return false
+	}
+	if errorResp, ok := response.(types.ErrorResponse); ok {
+		fmt.Fprintf(c.out, "Error invoking Bedrock agent: %s\n", errorResp.Error)
+		return false
+	}
+	answer, ok := response.(types.AgentResponse)
+	if !ok {
+		fmt.Fprintf(c.out, "%v\n", response)
+		return true
+	}
+
+	responseText := answer.Response + handlers.FormatCitations(answer.Citations)
+	if answer.Guardrail != nil {
+		responseText = handlers.GuardrailMessage(answer.Guardrail)
+		if streamed {
+			fmt.Fprintln(c.out)
+		}
+	}
+	switch {
+	case answer.Guardrail != nil:
+		fmt.Fprintln(c.out, responseText)
+	case c.slackFormat:
+		for i, blocks := range utils.RenderMessages(responseText) {
+			fmt.Fprintf(c.out, "--- Slack message %d ---\n", i+1)
+			for _, block := range blocks {
+				if section, ok := block.(*slack.SectionBlock); ok && section.Text != nil {
+					fmt.Fprintln(c.out, section.Text.Text)
+				}
+			}
+		}
+	case streamed:
+		fmt.Fprintln(c.out, handlers.FormatCitations(answer.Citations))
+	default:
+		fmt.Fprintln(c.out, responseText)
+	}
+
+	if answer.Traceback != "" {
+		fmt.Fprintf(c.out, "\n%s\n", answer.Traceback)
+	}
+	fmt.Fprintf(c.out, "\n(%s, session %s)\n", time.Since(start).Round(time.Millisecond), c.sessionID)
+
+	return true
+}
+
+// retrieve prints the knowledge base passages matching a query
+func (c *chatSession) retrieve(query string) bool {
+	response, err := c.bedrockService.RetrieveFromKnowledgeBase(query, services.DefaultRetrieveResults, nil)
+	if err != nil {
+		fmt.Fprintf(c.out, "Error retrieving from knowledge base: %v\n", err)
+		return false
+	}
+	if errorResp, ok := response.(types.ErrorResponse); ok {
+		fmt.Fprintf(c.out, "Error retrieving from knowledge base: %s\n", errorResp.Error)
+		return false
+	}
+	results, ok := response.(types.RetrievalResults)
+	if !ok {
+		fmt.Fprintf(c.out, "%v\n", response)
+		return true
+	}
+
+	fmt.Fprintf(c.out, "%d results for: %s\n", len(results.Results), results.Query)
+	for i, result := range results.Results {
+		fmt.Fprintf(c.out, "\n%d. [%.3f] %s\n%s\n", i+1, result.Score, result.Source, result.Text)
+	}
+	return true
+}
+
+// loadAttachments reads files and encodes them the way Slack attachments are passed to the agent
+func loadAttachments(paths []string) ([]types.FileAttachment, error) {
+	attachments := []types.FileAttachment{}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attachment: %w", err)
+		}
+		attachments = append(attachments, types.FileAttachment{
+			Name:      filepath.Base(path),
+			Data:      base64.StdEncoding.EncodeToString(content),
+			MediaType: utils.GetFileType(path),
+		})
+	}
+	return attachments, nil
+}
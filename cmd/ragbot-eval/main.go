@@ -0,0 +1,145 @@
+// Command ragbot-eval replays a golden set of questions through the answering backend and
+// scores the answers, so agent alias promotions can be gated on regression results.
+//
+// Usage:
+//
+//	ragbot-eval -questions golden.jsonl [-alias NAME|ALIASID] [-junit report.xml] [-json report.json]
+//
+// It reads the same environment as the server (including .env), so RAGBOT_BACKEND=fake
+// evaluates against canned answers without calling AWS. The exit status is 1 when any
+// question fails.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"slack-rag-server/src/services"
+	"slack-rag-server/src/types"
+)
+
+// invalidSessionChars matches the characters Bedrock does not accept in session IDs
+var invalidSessionChars = regexp.MustCompile(`[^0-9a-zA-Z._:-]`)
+
+// maxSessionIDLength is the longest session ID Bedrock accepts
+const maxSessionIDLength = 100
+
+func main() {
+	questionsPath := flag.String("questions", "", "JSONL file of golden questions (required)")
+	alias := flag.String("alias", "", "Agent alias to evaluate instead of AWS_BEDROCK_AGENT_ALIAS_ID, by name from RAGBOT_AGENT_ALIASES or by ID")
+	junitPath := flag.String("junit", "", "Write a JUnit XML report to this file")
+	jsonPath := flag.String("json", "", "Write a JSON report to this file")
+	maxLatency := flag.Duration("max-latency", 0, "Fail answers slower than this, unless a question sets its own limit")
+	flag.Parse()
+
+	if *questionsPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: Error loading .env file:", err)
+	}
+
+	cases, err := loadCases(*questionsPath)
+	if err != nil {
+		log.Fatalf("Failed to load questions: %v", err)
+	}
+
+	bedrockService, err := services.NewBedrockService()
+	if err != nil {
+		log.Fatalf("Failed to initialize Bedrock service: %v", err)
+	}
+
+	// Accept the alias names questions use with --agent, as well as raw alias IDs
+	aliasID := *alias
+	if resolved, ok := bedrockService.ResolveAgentAlias(aliasID); ok {
+		aliasID = resolved
+	}
+
+	report := evalReport{
+		Backend:   bedrockService.BackendName(),
+		AliasID:   aliasID,
+		StartedAt: time.Now(),
+	}
+	for _, evalCase := range cases {
+		result := runCase(bedrockService, evalCase, aliasID, *maxLatency)
+		report.add(result)
+
+		status := "PASS"
+		if !result.Passed {
+			status = "FAIL"
+		}
+		fmt.Printf("%s %s (score %.2f, %s)\n", status, result.ID, result.Score, result.Latency.Round(time.Millisecond))
+		for _, failure := range result.Failures {
+			fmt.Printf("    %s\n", failure)
+		}
+	}
+	report.Duration = time.Since(report.StartedAt)
+	report.DurationMs = report.Duration.Milliseconds()
+
+	fmt.Printf("\n%d/%d passed, average score %.2f, average latency %s\n",
+		report.Passed, report.Total, report.AverageScore, report.AverageLatency.Round(time.Millisecond))
+
+	if *jsonPath != "" {
+		if err := writeJSONReport(*jsonPath, report); err != nil {
+			log.Fatalf("Failed to write JSON report: %v", err)
+		}
+	}
+	if *junitPath != "" {
+		if err := writeJUnitReport(*junitPath, report); err != nil {
+			log.Fatalf("Failed to write JUnit report: %v", err)
+		}
+	}
+
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runCase asks a golden question in a fresh session and scores the answer
+func runCase(bedrockService *services.BedrockService, evalCase evalCase, aliasID string, maxLatency time.Duration) caseResult {
+	sessionID := evalSessionID(evalCase.ID, time.Now())
+	defer bedrockService.EndSession(sessionID, aliasID)
+
+	start := time.Now()
+	response, err := bedrockService.Answer(evalCase.Question, sessionID, nil, false, types.SessionContext{AgentAliasID: aliasID})
+	latency := time.Since(start)
+
+	if evalCase.MaxLatencyMs > 0 {
+		maxLatency = time.Duration(evalCase.MaxLatencyMs) * time.Millisecond
+	}
+
+	if err != nil {
+		return failedCase(evalCase, latency, err.Error())
+	}
+	if errorResp, ok := response.(types.ErrorResponse); ok {
+		return failedCase(evalCase, latency, errorResp.Error)
+	}
+	answer, ok := response.(types.AgentResponse)
+	if !ok {
+		return failedCase(evalCase, latency, fmt.Sprintf("unexpected response: %v", response))
+	}
+	if answer.Guardrail != nil {
+		return failedCase(evalCase, latency, fmt.Sprintf("blocked by guardrail at %s: %s", answer.Guardrail.Stage, services.FormatGuardrailPolicies(answer.Guardrail.Policies)))
+	}
+
+	return scoreAnswer(evalCase, answer, latency, maxLatency)
+}
+
+// evalSessionID returns a fresh session ID for a golden question, shortening long question IDs
+// to fit Bedrock's limit
+func evalSessionID(caseID string, now time.Time) string {
+	suffix := fmt.Sprintf("-%d", now.UnixNano())
+	caseID = invalidSessionChars.ReplaceAllString(caseID, "-")
+	if limit := maxSessionIDLength - len("eval-") - len(suffix); len(caseID) > limit {
+		caseID = caseID[:limit]
+	}
+	return "eval-" + caseID + suffix
+}
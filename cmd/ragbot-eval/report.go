@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// evalReport summarizes a run over the golden questions
+type evalReport struct {
+	Backend          string        `json:"backend"`
+	AliasID          string        `json:"aliasId,omitempty"`
+	StartedAt        time.Time     `json:"startedAt"`
+	Duration         time.Duration `json:"-"`
+	DurationMs       int64         `json:"durationMs"`
+	Total            int           `json:"total"`
+	Passed           int           `json:"passed"`
+	Failed           int           `json:"failed"`
+	AverageScore     float64       `json:"averageScore"`
+	AverageLatency   time.Duration `json:"-"`
+	AverageLatencyMs int64         `json:"averageLatencyMs"`
+	Cases            []caseResult  `json:"cases"`
+}
+
+// add adds a scored question to the report and updates the totals
+func (r *evalReport) add(result caseResult) {
+	r.Cases = append(r.Cases, result)
+	r.Total++
+	if result.Passed {
+		r.Passed++
+	} else {
+		r.Failed++
+	}
+
+	var score float64
+	var latency time.Duration
+	for _, c := range r.Cases {
+		score += c.Score
+		latency += c.Latency
+	}
+	r.AverageScore = score / float64(r.Total)
+	r.AverageLatency = latency / time.Duration(r.Total)
+	r.AverageLatencyMs = r.AverageLatency.Milliseconds()
+}
+
+// writeJSONReport writes the report as indented JSON
+func writeJSONReport(path string, report evalReport) error {
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0o644)
+}
+
+// junitTestSuite is the root element of a JUnit XML report
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is a golden question in a JUnit XML report
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// junitFailure describes why a question failed
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnitReport writes the report as JUnit XML, one test case per question, so CI systems
+// can show the results and fail the build on regressions
+func writeJUnitReport(path string, report evalReport) error {
+	suite := junitTestSuite{
+		Name:      "ragbot-eval",
+		Tests:     report.Total,
+		Time:      junitSeconds(report.Duration),
+		Timestamp: report.StartedAt.UTC().Format("2006-01-02T15:04:05"),
+	}
+
+	for _, result := range report.Cases {
+		testCase := junitTestCase{
+			Name:      result.ID,
+			ClassName: "ragbot-eval." + report.Backend,
+			Time:      junitSeconds(result.Latency),
+			SystemOut: fmt.Sprintf("Question: %s\n\nAnswer: %s\n\nSources: %s\n\nScore: %.2f",
+				result.Question, result.Answer, strings.Join(result.Sources, ", "), result.Score),
+		}
+
+		switch {
+		case result.Error != "":
+			testCase.Error = &junitFailure{Message: result.Error, Text: strings.Join(result.Failures, "\n")}
+			suite.Errors++
+		case !result.Passed:
+			testCase.Failure = &junitFailure{Message: strings.Join(result.Failures, "; "), Text: strings.Join(result.Failures, "\n")}
+			suite.Failures++
+		}
+
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	content, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), append(content, '\n')...), 0o644)
+}
+
+// junitSeconds formats a duration as the seconds JUnit reports use
+func junitSeconds(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
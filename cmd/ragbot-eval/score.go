@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"slack-rag-server/src/types"
+)
+
+// evalCase is a golden question with the facts its answer must state and the sources it must cite
+type evalCase struct {
+	ID              string   `json:"id"`
+	Question        string   `json:"question"`
+	ExpectedFacts   []string `json:"expectedFacts"`
+	RequiredSources []string `json:"requiredSources"`
+	MaxLatencyMs    int      `json:"maxLatencyMs,omitempty"`
+}
+
+// caseResult is the scored answer to a golden question
+type caseResult struct {
+	ID             string        `json:"id"`
+	Question       string        `json:"question"`
+	Answer         string        `json:"answer,omitempty"`
+	Error          string        `json:"error,omitempty"`
+	Sources        []string      `json:"sources,omitempty"`
+	MissingFacts   []string      `json:"missingFacts,omitempty"`
+	MissingSources []string      `json:"missingSources,omitempty"`
+	Latency        time.Duration `json:"-"`
+	LatencyMs      int64         `json:"latencyMs"`
+	Score          float64       `json:"score"`
+	Passed         bool          `json:"passed"`
+	Failures       []string      `json:"failures,omitempty"`
+}
+
+// loadCases reads golden questions from a JSONL file, one question per line
+func loadCases(path string) ([]evalCase, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var cases []evalCase
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "//") {
+			continue
+		}
+
+		var evalCase evalCase
+		if err := json.Unmarshal([]byte(text), &evalCase); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if evalCase.Question == "" {
+			return nil, fmt.Errorf("line %d: question is required", line)
+		}
+		if evalCase.ID == "" {
+			evalCase.ID = fmt.Sprintf("line-%d", line)
+		}
+		cases = append(cases, evalCase)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("%s has no questions", path)
+	}
+
+	return cases, nil
+}
+
+// scoreAnswer scores an answer by the share of expected facts it states and required sources
+// it cites. It passes when every fact and source is present within the latency limit.
+func scoreAnswer(evalCase evalCase, answer types.AgentResponse, latency, maxLatency time.Duration) caseResult {
+	result := caseResult{
+		ID:        evalCase.ID,
+		Question:  evalCase.Question,
+		Answer:    answer.Response,
+		Latency:   latency,
+		LatencyMs: latency.Milliseconds(),
+	}
+
+	for _, citation := range answer.Citations {
+		result.Sources = append(result.Sources, citation.Sources...)
+	}
+
+	normalizedAnswer := normalizeText(answer.Response)
+	for _, fact := range evalCase.ExpectedFacts {
+		if !strings.Contains(normalizedAnswer, normalizeText(fact)) {
+			result.MissingFacts = append(result.MissingFacts, fact)
+		}
+	}
+	for _, source := range evalCase.RequiredSources {
+		if !citesSource(result.Sources, source) {
+			result.MissingSources = append(result.MissingSources, source)
+		}
+	}
+
+	checks := len(evalCase.ExpectedFacts) + len(evalCase.RequiredSources)
+	result.Score = 1
+	if checks > 0 {
+		result.Score = float64(checks-len(result.MissingFacts)-len(result.MissingSources)) / float64(checks)
+	}
+
+	if len(result.MissingFacts) > 0 {
+		result.Failures = append(result.Failures, "missing facts: "+strings.Join(result.MissingFacts, "; "))
+	}
+	if len(result.MissingSources) > 0 {
+		result.Failures = append(result.Failures, "missing sources: "+strings.Join(result.MissingSources, "; "))
+	}
+	if maxLatency > 0 && latency > maxLatency {
+		result.Failures = append(result.Failures, fmt.Sprintf("took %s, limit is %s", latency.Round(time.Millisecond), maxLatency))
+	}
+	result.Passed = len(result.Failures) == 0
+
+	return result
+}
+
+// failedCase is the result of a question the backend could not answer
+func failedCase(evalCase evalCase, latency time.Duration, message string) caseResult {
+	return caseResult{
+		ID:        evalCase.ID,
+		Question:  evalCase.Question,
+		Error:     message,
+		Latency:   latency,
+		LatencyMs: latency.Milliseconds(),
+		Failures:  []string{"error: " + message},
+	}
+}
+
+// citesSource reports whether any cited source contains the required source, ignoring case
+func citesSource(sources []string, required string) bool {
+	for _, source := range sources {
+		if strings.Contains(strings.ToLower(source), strings.ToLower(required)) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeText lowercases text and collapses whitespace so facts match regardless of formatting
+func normalizeText(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
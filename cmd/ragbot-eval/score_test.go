@@ -0,0 +1,153 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"slack-rag-server/src/types"
+)
+
+func TestScoreAnswer(t *testing.T) {
+	answer := types.AgentResponse{
+		Response: "Rotate the key in the **IAM console**,\nthen update   the Secrets Manager entry.",
+		Citations: []types.Citation{
+			{Sources: []string{"s3://docs/runbooks/Key-Rotation.md"}},
+			{Sources: []string{"https://wiki.example.com/security"}},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		evalCase       evalCase
+		latency        time.Duration
+		maxLatency     time.Duration
+		score          float64
+		passed         bool
+		missingFacts   []string
+		missingSources []string
+		failures       int
+	}{
+		{
+			name:     "no checks",
+			evalCase: evalCase{ID: "empty"},
+			score:    1,
+			passed:   true,
+		},
+		{
+			name: "facts ignore case and whitespace",
+			evalCase: evalCase{ID: "facts", ExpectedFacts: []string{
+				"iam CONSOLE",
+				"then update the secrets manager entry",
+			}},
+			score:  1,
+			passed: true,
+		},
+		{
+			name: "sources match by substring ignoring case",
+			evalCase: evalCase{ID: "sources", RequiredSources: []string{
+				"key-rotation.md",
+				"wiki.example.com",
+			}},
+			score:  1,
+			passed: true,
+		},
+		{
+			name: "partial credit",
+			evalCase: evalCase{
+				ID:              "partial",
+				ExpectedFacts:   []string{"IAM console", "KMS"},
+				RequiredSources: []string{"key-rotation", "onboarding.md"},
+			},
+			score:          0.5,
+			missingFacts:   []string{"KMS"},
+			missingSources: []string{"onboarding.md"},
+			failures:       2,
+		},
+		{
+			name:       "too slow",
+			evalCase:   evalCase{ID: "slow", ExpectedFacts: []string{"IAM console"}},
+			latency:    3 * time.Second,
+			maxLatency: 2 * time.Second,
+			score:      1,
+			failures:   1,
+		},
+		{
+			name:       "within the latency limit",
+			evalCase:   evalCase{ID: "fast"},
+			latency:    time.Second,
+			maxLatency: 2 * time.Second,
+			score:      1,
+			passed:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := scoreAnswer(test.evalCase, answer, test.latency, test.maxLatency)
+			if result.Score != test.score {
+				t.Errorf("Score = %v, want %v", result.Score, test.score)
+			}
+			if result.Passed != test.passed {
+				t.Errorf("Passed = %t, want %t (failures: %v)", result.Passed, test.passed, result.Failures)
+			}
+			if !reflect.DeepEqual(result.MissingFacts, test.missingFacts) {
+				t.Errorf("MissingFacts = %v, want %v", result.MissingFacts, test.missingFacts)
+			}
+			if !reflect.DeepEqual(result.MissingSources, test.missingSources) {
+				t.Errorf("MissingSources = %v, want %v", result.MissingSources, test.missingSources)
+			}
+			if len(result.Failures) != test.failures {
+				t.Errorf("Failures = %v, want %d", result.Failures, test.failures)
+			}
+			if len(result.Sources) != 2 {
+				t.Errorf("Sources = %v, want both cited sources", result.Sources)
+			}
+		})
+	}
+}
+
+func TestCitesSource(t *testing.T) {
+	sources := []string{"s3://docs/runbooks/Key-Rotation.md", "https://wiki.example.com/security"}
+
+	tests := []struct {
+		required string
+		want     bool
+	}{
+		{"s3://docs/runbooks/Key-Rotation.md", true},
+		{"KEY-ROTATION", true},
+		{"wiki.example.com/security", true},
+		{"runbooks/onboarding.md", false},
+		{"wiki.example.org", false},
+	}
+
+	for _, test := range tests {
+		if got := citesSource(sources, test.required); got != test.want {
+			t.Errorf("citesSource(%q) = %t, want %t", test.required, got, test.want)
+		}
+	}
+
+	if citesSource(nil, "anything") {
+		t.Errorf("citesSource without sources = true, want false")
+	}
+}
+
+func TestEvalSessionID(t *testing.T) {
+	now := time.Unix(1700000000, 123456789)
+
+	if got := evalSessionID("vpn/setup #2", now); got != "eval-vpn-setup--2-1700000000123456789" {
+		t.Errorf("evalSessionID() = %q", got)
+	}
+
+	long := evalSessionID(strings.Repeat("x", 200), now)
+	if len(long) != maxSessionIDLength {
+		t.Errorf("evalSessionID() of a long ID has length %d, want %d", len(long), maxSessionIDLength)
+	}
+	if !strings.HasSuffix(long, "-1700000000123456789") {
+		t.Errorf("evalSessionID() = %q, lost its timestamp", long)
+	}
+	if invalidSessionChars.MatchString(long) {
+		t.Errorf("evalSessionID() = %q has invalid characters", long)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/joho/godotenv"
+	"github.com/slack-go/slack"
+
+	"slack-rag-server/src/fakeslack"
+	"slack-rag-server/src/handlers"
+	"slack-rag-server/src/services"
+)
+
+// devSigningSecret signs the requests simulated in dev mode
+const devSigningSecret = "dev-signing-secret"
+
+// devMessage is a simulated Slack interaction sent from the dev UI or with curl
+type devMessage struct {
+	// Kind is "mention", "dm", "thread" (a thread reply in a channel) or "command"
+	Kind     string `json:"kind"`
+	User     string `json:"user"`
+	Channel  string `json:"channel"`
+	Text     string `json:"text"`
+	ThreadTS string `json:"threadTs"`
+}
+
+// devServer simulates a Slack workspace around the bot's handlers
+type devServer struct {
+	fake               *fakeslack.Server
+	baseURL            string
+	responses          atomic.Int64
+	messageHandler     *handlers.MessageHandler
+	commandHandler     *handlers.CommandHandler
+	homeHandler        *handlers.HomeHandler
+	interactionHandler *handlers.InteractionHandler
+}
+
+// runDev runs the server against an embedded fake Slack Web API, with a web page at /dev
+// for sending simulated mentions, direct messages and slash commands and watching the replies.
+// The dev endpoints are unauthenticated, so the server only listens on localhost and answers
+// with the fake backend unless -real-backend is given.
+func runDev(args []string) {
+	flags := flag.NewFlagSet("dev", flag.ExitOnError)
+	botUserID := flags.String("bot-user", fakeslack.DefaultBotUserID, "User ID of the simulated bot")
+	realBackend := flags.Bool("real-backend", false, "Answer with the backend set in RAGBOT_BACKEND (Bedrock by default) instead of the fake backend")
+	flags.Parse(args)
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: Error loading .env file:", err)
+	}
+
+	// Answer from canned answers, without calling AWS or running agent actions
+	if !*realBackend {
+		os.Setenv("RAGBOT_BACKEND", services.BackendFake)
+	}
+
+	fake := fakeslack.New()
+	fake.BotUserID = *botUserID
+	baseURL := "http://localhost:" + serverPort()
+
+	// All Slack Web API calls go to the fake server mounted on this server
+	api := slack.New("xoxb-dev",
+		slack.OptionAPIURL(baseURL+"/fake-slack/api/"),
+		slack.OptionLog(log.New(os.Stdout, "slack-bot: ", log.Lshortfile|log.LstdFlags)),
+	)
+
+	bedrockService, err := services.NewBedrockService()
+	if err != nil {
+		log.Fatalf("Failed to initialize Bedrock service: %v", err)
+	}
+	messageHandler, commandHandler, homeHandler, interactionHandler := initializeHandlers(api, bedrockService)
+
+	dev := &devServer{
+		fake:               fake,
+		baseURL:            baseURL,
+		messageHandler:     messageHandler,
+		commandHandler:     commandHandler,
+		homeHandler:        homeHandler,
+		interactionHandler: interactionHandler,
+	}
+
+	setupHTTPRoutes(devSigningSecret, messageHandler, commandHandler, homeHandler, interactionHandler)
+	http.Handle("/fake-slack/", http.StripPrefix("/fake-slack", fake))
+	http.HandleFunc("/dev", dev.handlePage)
+	http.HandleFunc("/dev/send", dev.handleSend)
+	http.HandleFunc("/dev/calls", dev.handleCalls)
+
+	log.Printf("Dev mode: open %s/dev to talk to the %s backend", baseURL, bedrockService.BackendName())
+	startServer("127.0.0.1:" + serverPort())
+}
+
+// handlePage serves the dev UI
+func (d *devServer) handlePage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(devPage))
+}
+
+// handleCalls lists the simulated messages and the Slack calls the bot made
+func (d *devServer) handleCalls(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete {
+		d.fake.Reset()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d.fake.Calls())
+}
+
+// handleSend simulates a user sending a message or slash command
+func (d *devServer) handleSend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var message devMessage
+	if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		http.Error(w, "invalid message: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if message.User == "" {
+		message.User = "U0DEVUSER"
+	}
+	if message.Channel == "" {
+		message.Channel = "C0DEV"
+	}
+
+	status, err := d.send(message)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(status)
+}
+
+// send builds the signed request Slack would send for a message and passes it to the handlers
+func (d *devServer) send(message devMessage) (int, error) {
+	var path, contentType, body string
+
+	switch message.Kind {
+	case "command":
+		command, text, _ := strings.Cut(strings.TrimSpace(message.Text), " ")
+		if !strings.HasPrefix(command, "/") {
+			command, text = handlers.CommandName, strings.TrimSpace(message.Text)
+		}
+		d.fake.UserMessage(message.Channel, message.User, strings.TrimSpace(command+" "+text), "")
+
+		path, contentType = "/slack/commands", "application/x-www-form-urlencoded"
+		body = url.Values{
+			"command":      {command},
+			"text":         {text},
+			"user_id":      {message.User},
+			"user_name":    {strings.ToLower(message.User)},
+			"channel_id":   {message.Channel},
+			"team_id":      {fakeslack.TeamID},
+			"response_url": {d.baseURL + "/fake-slack" + fakeslack.ResponseURLPath + strconv.FormatInt(d.responses.Add(1), 10)},
+		}.Encode()
+	case "mention", "dm", "thread":
+		event := map[string]interface{}{
+			"type": "message",
+			"user": message.User,
+			"text": message.Text,
+		}
+		switch message.Kind {
+		case "mention":
+			event["type"] = "app_mention"
+			event["text"] = fmt.Sprintf("<@%s> %s", d.fake.BotUserID, message.Text)
+		case "dm":
+			message.Channel = "D" + strings.TrimPrefix(message.User, "U")
+			event["channel_type"] = "im"
+		case "thread":
+			if message.ThreadTS == "" {
+				return 0, fmt.Errorf("thread replies need a threadTs")
+			}
+			event["channel_type"] = "channel"
+		}
+
+		posted := d.fake.UserMessage(message.Channel, message.User, event["text"].(string), message.ThreadTS)
+		event["channel"] = message.Channel
+		event["ts"] = posted.TS
+		if message.ThreadTS != "" {
+			event["thread_ts"] = message.ThreadTS
+		}
+
+		content, err := json.Marshal(map[string]interface{}{
+			"type":    "event_callback",
+			"team_id": fakeslack.TeamID,
+			"event":   event,
+		})
+		if err != nil {
+			return 0, err
+		}
+		path, contentType, body = "/slack/events", "application/json", string(content)
+	default:
+		return 0, fmt.Errorf("unknown kind %q, expected mention, dm, thread or command", message.Kind)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	signRequest(r, body, devSigningSecret)
+
+	w := httptest.NewRecorder()
+	if path == "/slack/commands" {
+		handleSlashCommand(w, r, devSigningSecret, d.commandHandler)
+	} else {
+		handleSlackEvents(w, r, devSigningSecret, d.messageHandler, d.commandHandler, d.homeHandler)
+	}
+	return w.Code, nil
+}
+
+// devPage is the dev UI: a form for simulated messages and a live view of the conversation
+const devPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Ragbot dev</title>
+<style>
+body { font-family: sans-serif; margin: 2em; max-width: 60em; }
+form { display: flex; gap: .5em; flex-wrap: wrap; margin-bottom: 1em; }
+input[name=text] { flex: 1; min-width: 20em; }
+.call { border-left: 3px solid #ccc; padding: .3em .8em; margin: .4em 0; white-space: pre-wrap; }
+.user { border-color: #1264a3; }
+.bot { border-color: #2eb67d; }
+.meta { color: #666; font-size: .85em; }
+</style>
+</head>
+<body>
+<h1>Ragbot dev</h1>
+<form id="send">
+  <select name="kind">
+    <option value="mention">@mention</option>
+    <option value="dm">Direct message</option>
+    <option value="thread">Thread reply</option>
+    <option value="command">Slash command</option>
+  </select>
+  <input name="user" value="U0DEVUSER" size="12" title="User ID">
+  <input name="channel" value="C0DEV" size="10" title="Channel ID">
+  <input name="threadTs" placeholder="thread ts" size="18" title="Thread timestamp (click a message to reply to it)">
+  <input name="text" placeholder="Ask a question, or /ragbot help" autofocus>
+  <button>Send</button>
+  <button type="button" id="clear">Clear</button>
+</form>
+<div id="calls"></div>
+<script>
+const form = document.getElementById('send');
+form.addEventListener('submit', async (e) => {
+  e.preventDefault();
+  const message = Object.fromEntries(new FormData(form));
+  const response = await fetch('/dev/send', { method: 'POST', body: JSON.stringify(message) });
+  if (!response.ok) alert(await response.text());
+  form.text.value = '';
+  refresh();
+});
+document.getElementById('clear').addEventListener('click', async () => {
+  await fetch('/dev/calls', { method: 'DELETE' });
+  refresh();
+});
+function describe(call) {
+  const p = call.params || {};
+  switch (call.method) {
+    case 'user.message': return ['user', p.user + ' in ' + p.channel, p.text];
+    case 'chat.postMessage': return ['bot', 'message in ' + p.channel, p.text];
+    case 'chat.postEphemeral': return ['bot', 'ephemeral to ' + p.user + ' in ' + p.channel, p.text];
+    case 'chat.update': return ['bot', 'updated ' + p.ts + ' in ' + p.channel, p.text];
+    case 'reactions.add': return ['bot', 'reacted in ' + p.channel, ':' + p.name + ':'];
+    case 'response_url': return ['bot', 'command response (' + (p.response_type || 'ephemeral') + ')', p.text];
+    default: return ['', call.method, JSON.stringify(p)];
+  }
+}
+async function refresh() {
+  const calls = await (await fetch('/dev/calls')).json();
+  const list = document.getElementById('calls');
+  list.innerHTML = '';
+  for (const call of calls || []) {
+    const [kind, title, text] = describe(call);
+    const thread = (call.params || {}).thread_ts;
+    const div = document.createElement('div');
+    div.className = 'call ' + kind;
+    const meta = document.createElement('div');
+    meta.className = 'meta';
+    meta.textContent = title + (call.ts ? ' · ts ' + call.ts : '') + (thread ? ' · thread ' + thread : '');
+    const body = document.createElement('div');
+    body.textContent = text || '';
+    div.append(meta, body);
+    if (call.ts) div.onclick = () => { form.threadTs.value = thread || call.ts; form.channel.value = call.params.channel; };
+    list.append(div);
+  }
+}
+setInterval(refresh, 1000);
+refresh();
+</script>
+</body>
+</html>
+`
@@ -3,11 +3,14 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/joho/godotenv"
 	"github.com/slack-go/slack"
@@ -17,19 +20,60 @@ import (
 	"slack-rag-server/src/services"
 )
 
+// background tracks the Slack requests still being processed after they were acknowledged,
+// so replays can wait for their responses
+var background sync.WaitGroup
+
+// inBackground processes a request in a separate goroutine
+func inBackground(process func()) {
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		process()
+	}()
+}
+
+// logRedactor masks secrets and personal data in the request bodies and messages logged
+var logRedactor *services.Redactor
+
+// redactForLog masks sensitive data in text before it is logged
+func redactForLog(text string) string {
+	if logRedactor == nil {
+		return text
+	}
+	return logRedactor.Redact(text).Text
+}
+
 func main() {
+	// Replay recorded Slack requests against fake Slack and Bedrock backends
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		runReplay(os.Args[2:])
+		return
+	}
+
+	// Run against an embedded fake Slack workspace for development
+	if len(os.Args) > 1 && os.Args[1] == "dev" {
+		runDev(os.Args[2:])
+		return
+	}
+
+	// Talk to the agent from the terminal
+	if len(os.Args) > 1 && os.Args[1] == "chat" {
+		runChat(os.Args[2:])
+		return
+	}
+
 	// Load environment variables and initialize services
 	api, signingSecret, bedrockService := initializeServices()
 
 	// Initialize handlers
-	messageHandler := handlers.NewMessageHandler(api, bedrockService)
-	commandHandler := handlers.NewCommandHandler(api, bedrockService)
+	messageHandler, commandHandler, homeHandler, interactionHandler := initializeHandlers(api, bedrockService)
 
 	// Set up HTTP server with endpoints
-	setupHTTPRoutes(signingSecret, messageHandler, commandHandler)
+	setupHTTPRoutes(signingSecret, messageHandler, commandHandler, homeHandler, interactionHandler)
 
 	// Start HTTP server
-	startServer()
+	startServer(":" + serverPort())
 }
 
 func initializeServices() (*slack.Client, string, *services.BedrockService) {
@@ -62,19 +106,71 @@ func initializeServices() (*slack.Client, string, *services.BedrockService) {
 	return api, signingSecret, bedrockService
 }
 
-func setupHTTPRoutes(signingSecret string, messageHandler *handlers.MessageHandler, commandHandler *handlers.CommandHandler) {
+// initializeHandlers creates the stores and services the handlers share, and the handlers themselves
+func initializeHandlers(api *slack.Client, bedrockService *services.BedrockService) (*handlers.MessageHandler, *handlers.CommandHandler, *handlers.HomeHandler, *handlers.InteractionHandler) {
+	history := services.NewHistoryStore()
+	directory := services.NewSlackDirectory(api)
+	sessions, err := services.NewSessionStore()
+	if err != nil {
+		log.Fatalf("Failed to load sessions: %v", err)
+	}
+	filters, err := services.NewFilterPolicy(directory)
+	if err != nil {
+		log.Fatalf("Failed to load knowledge base filters: %v", err)
+	}
+	audit, err := services.NewAuditLog()
+	if err != nil {
+		log.Fatalf("Failed to open audit log: %v", err)
+	}
+	actions, err := services.NewActionRegistry(audit)
+	if err != nil {
+		log.Fatalf("Failed to load agent actions: %v", err)
+	}
+	bedrockService.SetActionRegistry(actions)
+	scheduler, err := services.NewSyncScheduler(bedrockService, api)
+	if err != nil {
+		log.Fatalf("Failed to load sync schedules: %v", err)
+	}
+	scheduler.Start()
+	experiment, err := services.NewAliasExperiment(bedrockService)
+	if err != nil {
+		log.Fatalf("Failed to load agent alias experiment: %v", err)
+	}
+	redactor, err := services.NewRedactor()
+	if err != nil {
+		log.Fatalf("Failed to load redaction settings: %v", err)
+	}
+	logRedactor = redactor
+
+	messageHandler := handlers.NewMessageHandler(api, bedrockService, history, directory, sessions, filters, experiment, redactor, audit)
+	commandHandler := handlers.NewCommandHandler(api, bedrockService, filters, sessions, history, scheduler, experiment)
+	homeHandler := handlers.NewHomeHandler(api, bedrockService, history)
+	interactionHandler := handlers.NewInteractionHandler(api, messageHandler, homeHandler, commandHandler)
+
+	return messageHandler, commandHandler, homeHandler, interactionHandler
+}
+
+func setupHTTPRoutes(signingSecret string, messageHandler *handlers.MessageHandler, commandHandler *handlers.CommandHandler, homeHandler *handlers.HomeHandler, interactionHandler *handlers.InteractionHandler) {
 	// Health check endpoint
 	http.HandleFunc("/health-check", healthCheckHandler)
 
+	// Record Slack requests for replay if RAGBOT_RECORD_FILE is set
+	recorder := newRequestRecorder()
+
 	// Slack events endpoint
-	http.HandleFunc("/slack/events", func(w http.ResponseWriter, r *http.Request) {
-		handleSlackEvents(w, r, signingSecret, messageHandler, commandHandler)
-	})
+	http.HandleFunc("/slack/events", recorder.wrap(func(w http.ResponseWriter, r *http.Request) {
+		handleSlackEvents(w, r, signingSecret, messageHandler, commandHandler, homeHandler)
+	}))
 
 	// Slash commands endpoint
-	http.HandleFunc("/slack/commands", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/slack/commands", recorder.wrap(func(w http.ResponseWriter, r *http.Request) {
 		handleSlashCommand(w, r, signingSecret, commandHandler)
-	})
+	}))
+
+	// Interactivity endpoint (buttons, shortcuts and modals)
+	http.HandleFunc("/slack/interactions", recorder.wrap(func(w http.ResponseWriter, r *http.Request) {
+		handleInteraction(w, r, signingSecret, interactionHandler)
+	}))
 }
 
 func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
@@ -82,23 +178,27 @@ func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Health check passed"))
 }
 
-func startServer() {
-	// Get port from environment variable, default to 8083
+// serverPort returns the port to listen on from the PORT environment variable, default 8083
+func serverPort() string {
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8083"
 	}
+	return port
+}
 
+// startServer serves the registered routes on an address such as ":8083"
+func startServer(addr string) {
 	// Start HTTP server
-	log.Printf("Starting HTTP server on port %s", port)
+	log.Printf("Starting HTTP server on %s", addr)
 	log.Println("⚡️ RagBot is running!")
 
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	if err := http.ListenAndServe(addr, nil); err != nil {
 		log.Fatalf("Error starting HTTP server: %v", err)
 	}
 }
 
-func handleSlackEvents(w http.ResponseWriter, r *http.Request, signingSecret string, messageHandler *handlers.MessageHandler, commandHandler *handlers.CommandHandler) {
+func handleSlackEvents(w http.ResponseWriter, r *http.Request, signingSecret string, messageHandler *handlers.MessageHandler, commandHandler *handlers.CommandHandler, homeHandler *handlers.HomeHandler) {
 	// Read the request body
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -108,7 +208,7 @@ func handleSlackEvents(w http.ResponseWriter, r *http.Request, signingSecret str
 	}
 
 	// Log the body for debugging
-	bodyString := string(body)
+	bodyString := redactForLog(string(body))
 	if len(bodyString) > 0 {
 		previewLength := len(bodyString)
 		if previewLength > 300 {
@@ -140,7 +240,7 @@ func handleSlackEvents(w http.ResponseWriter, r *http.Request, signingSecret str
 	}
 
 	// Process events in a separate goroutine to respond to Slack quickly
-	go processSlackEvent(body, messageHandler)
+	inBackground(func() { processSlackEvent(body, messageHandler, homeHandler) })
 
 	// Acknowledge receipt of the event
 	w.WriteHeader(http.StatusOK)
@@ -184,7 +284,7 @@ func verifySlackRequest(w http.ResponseWriter, r *http.Request, body []byte, sig
 	return true
 }
 
-func processSlackEvent(body []byte, messageHandler *handlers.MessageHandler) {
+func processSlackEvent(body []byte, messageHandler *handlers.MessageHandler, homeHandler *handlers.HomeHandler) {
 	// Parse the raw JSON to access the event property
 	var slackEvent map[string]interface{}
 	if err := json.Unmarshal(body, &slackEvent); err != nil {
@@ -214,6 +314,8 @@ func processSlackEvent(body []byte, messageHandler *handlers.MessageHandler) {
 		handleAppMentionEvent(eventObj, messageHandler)
 	case "message":
 		handleMessageEvent(eventObj, messageHandler)
+	case "app_home_opened":
+		handleAppHomeOpenedEvent(eventObj, homeHandler)
 	default:
 		log.Printf("Unhandled event type: %s", eventType)
 	}
@@ -233,10 +335,28 @@ func handleAppMentionEvent(eventObj map[string]interface{}, messageHandler *hand
 		return
 	}
 
-	log.Printf("Handling app mention from user %s: %s", appMentionEvent.User, appMentionEvent.Text)
+	log.Printf("Handling app mention from user %s: %s", appMentionEvent.User, redactForLog(appMentionEvent.Text))
 	messageHandler.HandleAppMention(&appMentionEvent)
 }
 
+func handleAppHomeOpenedEvent(eventObj map[string]interface{}, homeHandler *handlers.HomeHandler) {
+	// Convert the event back to JSON to parse it into the correct struct
+	eventBytes, err := json.Marshal(eventObj)
+	if err != nil {
+		log.Printf("Error marshalling app_home_opened event: %v", err)
+		return
+	}
+
+	var appHomeOpenedEvent slackevents.AppHomeOpenedEvent
+	if err := json.Unmarshal(eventBytes, &appHomeOpenedEvent); err != nil {
+		log.Printf("Error parsing app_home_opened event: %v", err)
+		return
+	}
+
+	log.Printf("Handling app home opened by user %s", appHomeOpenedEvent.User)
+	homeHandler.HandleAppHomeOpened(appHomeOpenedEvent.User, appHomeOpenedEvent.Tab)
+}
+
 func handleMessageEvent(eventObj map[string]interface{}, messageHandler *handlers.MessageHandler) {
 	// Convert the event back to JSON to parse it into the correct struct
 	eventBytes, err := json.Marshal(eventObj)
@@ -276,7 +396,9 @@ func handleSlashCommand(w http.ResponseWriter, r *http.Request, signingSecret st
 		}
 
 		bodyString = string(bodyBytes)
-		log.Printf("Raw body: %s", bodyString)
+		if decoded, err := url.QueryUnescape(bodyString); err == nil {
+			log.Printf("Raw body: %s", redactForLog(decoded))
+		}
 
 		// Reset the body so it can be read again for form parsing
 		r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
@@ -308,7 +430,7 @@ func handleSlashCommand(w http.ResponseWriter, r *http.Request, signingSecret st
 	}
 
 	// Log the form data for debugging
-	log.Printf("Slash command received - Form data: %+v", r.Form)
+	log.Printf("Slash command received - Form data: %s", redactForLog(fmt.Sprintf("%+v", r.Form)))
 
 	// Get command directly from form
 	command := r.Form.Get("command")
@@ -337,7 +459,7 @@ func handleSlashCommand(w http.ResponseWriter, r *http.Request, signingSecret st
 	log.Printf("Processing slash command: %s from user %s", s.Command, s.UserID)
 
 	// Process commands in a separate goroutine
-	go processSlashCommand(s, commandHandler)
+	inBackground(func() { processSlashCommand(s, commandHandler) })
 
 	// Acknowledge receipt of the command to Slack (required within 3 seconds)
 	// Don't send any content since we'll use the response_url to send the actual response
@@ -345,26 +467,43 @@ func handleSlashCommand(w http.ResponseWriter, r *http.Request, signingSecret st
 }
 
 func processSlashCommand(s slack.SlashCommand, commandHandler *handlers.CommandHandler) {
-	switch s.Command {
-	case "/ragbot-get-datasource":
-		commandHandler.HandleGetDataSource(s)
-	case "/ragbot-sync-datasource":
-		commandHandler.HandleSyncDataSource(s)
-	case "/ragbot-help":
-		commandHandler.HandleHelp(s)
-	case "/ragbot-kb-status":
-		commandHandler.HandleKbStatus(s)
-	case "/ragbot-ds-config":
-		commandHandler.HandleDsConfig(s)
-	case "/ragbot-agent-status":
-		commandHandler.HandleAgentStatus(s)
-	case "/ragbot-list-datasources":
-		commandHandler.HandleListDataSources(s)
-	case "/ragbot-job-status":
-		commandHandler.HandleJobStatus(s)
-	case "/ragbot-health-check":
-		commandHandler.HandleHealthCheck(s)
-	default:
-		log.Printf("Unknown command: %s", s.Command)
+	commandHandler.Dispatch(s)
+}
+
+// handleInteraction processes Slack interactivity payloads (block actions, shortcuts, view submissions)
+func handleInteraction(w http.ResponseWriter, r *http.Request, signingSecret string, interactionHandler *handlers.InteractionHandler) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error reading request body: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
 	}
+
+	// Verify request comes from Slack
+	if !verifySlackRequest(w, r, body, signingSecret) {
+		return
+	}
+
+	// Reset the body so it can be read again for form parsing
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing form: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var callback slack.InteractionCallback
+	if err := json.Unmarshal([]byte(r.Form.Get("payload")), &callback); err != nil {
+		log.Printf("Error parsing interaction payload: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("Processing interaction of type %s from user %s", callback.Type, callback.User.ID)
+
+	// Process interactions in a separate goroutine to respond to Slack quickly
+	inBackground(func() { interactionHandler.HandleInteraction(callback) })
+
+	// Acknowledge receipt of the interaction
+	w.WriteHeader(http.StatusOK)
 }
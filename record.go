@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// recordedRequest is a raw Slack request as received, with its signature headers
+type recordedRequest struct {
+	Time    time.Time   `json:"time"`
+	Path    string      `json:"path"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body"`
+}
+
+// requestRecorder appends the Slack requests the server receives to the JSON lines file
+// named in RAGBOT_RECORD_FILE, so they can be replayed later
+type requestRecorder struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// newRequestRecorder opens the recording file, returning nil when recording is off
+func newRequestRecorder() *requestRecorder {
+	path := os.Getenv("RAGBOT_RECORD_FILE")
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		log.Fatalf("Failed to open recording file: %v", err)
+	}
+
+	log.Printf("Recording Slack requests to %s", path)
+	return &requestRecorder{file: file}
+}
+
+// wrap records every request to a handler before handling it
+func (rec *requestRecorder) wrap(handler http.HandlerFunc) http.HandlerFunc {
+	if rec == nil {
+		return handler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			log.Printf("Error reading request body: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		rec.record(recordedRequest{
+			Time:    time.Now(),
+			Path:    r.URL.Path,
+			Headers: r.Header.Clone(),
+			Body:    string(body),
+		})
+
+		handler(w, r)
+	}
+}
+
+// record appends a request to the recording file
+func (rec *requestRecorder) record(request recordedRequest) {
+	line, err := json.Marshal(request)
+	if err != nil {
+		log.Printf("Error encoding recorded request: %v", err)
+		return
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if _, err := rec.file.Write(append(line, '\n')); err != nil {
+		log.Printf("Error writing recorded request: %v", err)
+	}
+}
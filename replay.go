@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+
+	"slack-rag-server/src/fakeslack"
+	"slack-rag-server/src/services"
+)
+
+// replaySigningSecret signs replayed requests, since recorded signatures expire after five minutes
+const replaySigningSecret = "replay-signing-secret"
+
+// replayedMethods are the Slack calls compared between replays, the ones users can see
+var replayedMethods = map[string]bool{
+	"chat.postMessage":             true,
+	"chat.postEphemeral":           true,
+	"chat.update":                  true,
+	"reactions.add":                true,
+	"files.completeUploadExternal": true,
+	"views.publish":                true,
+	"views.open":                   true,
+	"response_url":                 true,
+}
+
+// replayCall is a call the bot made to Slack while handling a replayed request, reduced to
+// the fields that are stable from one replay to the next
+type replayCall struct {
+	Request      int    `json:"request"`
+	Method       string `json:"method"`
+	Channel      string `json:"channel,omitempty"`
+	User         string `json:"user,omitempty"`
+	ThreadTS     string `json:"threadTs,omitempty"`
+	Reaction     string `json:"reaction,omitempty"`
+	ResponseType string `json:"responseType,omitempty"`
+	Text         string `json:"text,omitempty"`
+}
+
+// runReplay feeds recorded Slack requests through the same handlers as the server, with a
+// fake Slack API and the fake answering backend, and prints or checks the calls the bot made
+func runReplay(args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	expectPath := flags.String("expect", "", "Compare the calls with the JSON file of expected calls")
+	update := flags.Bool("update", false, "Write the calls to the -expect file instead of comparing them")
+	answersPath := flags.String("answers", "", "JSON file of canned answers for the fake backend (RAGBOT_FAKE_ANSWERS_FILE)")
+	botUserID := flags.String("bot-user", fakeslack.DefaultBotUserID, "User ID of the bot in the recording, so mentions of it are recognized")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: slack-rag-server replay [flags] recording.jsonl")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 || (*update && *expectPath == "") {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	requests, err := loadRecording(flags.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to load recording: %v", err)
+	}
+
+	// Answer from canned answers instead of Bedrock
+	os.Setenv("RAGBOT_BACKEND", services.BackendFake)
+	if *answersPath != "" {
+		os.Setenv("RAGBOT_FAKE_ANSWERS_FILE", *answersPath)
+	}
+
+	calls, err := replayRequests(requests, *botUserID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch {
+	case *update:
+		if err := writeReplayCalls(*expectPath, calls); err != nil {
+			log.Fatalf("Failed to write expected calls: %v", err)
+		}
+		fmt.Printf("Wrote %d calls for %d requests to %s\n", len(calls), len(requests), *expectPath)
+	case *expectPath != "":
+		expected, err := readReplayCalls(*expectPath)
+		if err != nil {
+			log.Fatalf("Failed to read expected calls: %v", err)
+		}
+		if !compareReplayCalls(expected, calls) {
+			os.Exit(1)
+		}
+		fmt.Printf("OK: %d requests produced the %d expected calls\n", len(requests), len(calls))
+	default:
+		for _, call := range calls {
+			line, _ := json.Marshal(call)
+			fmt.Println(string(line))
+		}
+	}
+}
+
+// replayRequests feeds recorded requests one at a time through the server's handlers, with a
+// fake Slack API, and returns the calls users can see that the bot made for each. The
+// answering backend is configured from the environment.
+func replayRequests(requests []recordedRequest, botUserID string) ([]replayCall, error) {
+	fake := fakeslack.New()
+	fake.BotUserID = botUserID
+	fakeServer := httptest.NewServer(fake)
+	defer fakeServer.Close()
+
+	api := slack.New("xoxb-replay", slack.OptionAPIURL(fakeServer.URL+"/api/"))
+	bedrockService, err := services.NewBedrockService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Bedrock service: %w", err)
+	}
+	messageHandler, commandHandler, homeHandler, interactionHandler := initializeHandlers(api, bedrockService)
+
+	calls := []replayCall{}
+	for i, request := range requests {
+		body := withFakeResponseURL(request, fakeServer.URL+fakeslack.ResponseURLPath+strconv.Itoa(i))
+		r := httptest.NewRequest(http.MethodPost, request.Path, strings.NewReader(body))
+		r.Header.Set("Content-Type", request.Headers.Get("Content-Type"))
+		signRequest(r, body, replaySigningSecret)
+
+		w := httptest.NewRecorder()
+		switch request.Path {
+		case "/slack/events":
+			handleSlackEvents(w, r, replaySigningSecret, messageHandler, commandHandler, homeHandler)
+		case "/slack/commands":
+			handleSlashCommand(w, r, replaySigningSecret, commandHandler)
+		case "/slack/interactions":
+			handleInteraction(w, r, replaySigningSecret, interactionHandler)
+		default:
+			log.Printf("Skipping request %d to unknown path %s", i, request.Path)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			log.Printf("Request %d to %s was answered with status %d", i, request.Path, w.Code)
+		}
+
+		// Wait for the request to be processed before replaying the next one
+		background.Wait()
+		calls = append(calls, replayCalls(i, fake.Calls())...)
+		fake.Reset()
+	}
+
+	return calls, nil
+}
+
+// loadRecording reads the requests recorded with RAGBOT_RECORD_FILE
+func loadRecording(path string) ([]recordedRequest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var requests []recordedRequest
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var request recordedRequest
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		requests = append(requests, request)
+	}
+	return requests, scanner.Err()
+}
+
+// withFakeResponseURL points the response URL of a slash command or interaction at the
+// fake Slack server. Event bodies are returned unchanged.
+func withFakeResponseURL(request recordedRequest, responseURL string) string {
+	if !strings.HasPrefix(request.Headers.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		return request.Body
+	}
+
+	form, err := url.ParseQuery(request.Body)
+	if err != nil {
+		return request.Body
+	}
+
+	if form.Get("response_url") != "" {
+		form.Set("response_url", responseURL)
+	}
+	if payload := form.Get("payload"); payload != "" {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(payload), &fields); err == nil {
+			if _, ok := fields["response_url"]; ok {
+				fields["response_url"] = responseURL
+			}
+			if rewritten, err := json.Marshal(fields); err == nil {
+				form.Set("payload", string(rewritten))
+			}
+		}
+	}
+
+	return form.Encode()
+}
+
+// signRequest signs a request the way Slack does, with the given secret and the current time
+func signRequest(r *http.Request, body, signingSecret string) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+
+	r.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+}
+
+// replayCalls reduces the calls made for a request to the ones users can see
+func replayCalls(request int, calls []fakeslack.Call) []replayCall {
+	result := []replayCall{}
+	for _, call := range calls {
+		if !replayedMethods[call.Method] {
+			continue
+		}
+		result = append(result, replayCall{
+			Request:      request,
+			Method:       call.Method,
+			Channel:      call.Params["channel"],
+			User:         call.Params["user"],
+			ThreadTS:     call.Params["thread_ts"],
+			Reaction:     call.Params["name"],
+			ResponseType: call.Params["response_type"],
+			Text:         call.Params["text"],
+		})
+	}
+	return result
+}
+
+// writeReplayCalls writes the expected calls as indented JSON
+func writeReplayCalls(path string, calls []replayCall) error {
+	content, err := json.MarshalIndent(calls, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0o644)
+}
+
+// readReplayCalls reads the expected calls
+func readReplayCalls(path string) ([]replayCall, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var calls []replayCall
+	err = json.Unmarshal(content, &calls)
+	return calls, err
+}
+
+// compareReplayCalls reports every difference between the expected and actual calls
+func compareReplayCalls(expected, actual []replayCall) bool {
+	matches := true
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		switch {
+		case i >= len(actual):
+			fmt.Printf("Missing call %d: %s\n", i, formatReplayCall(expected[i]))
+		case i >= len(expected):
+			fmt.Printf("Unexpected call %d: %s\n", i, formatReplayCall(actual[i]))
+		case !reflect.DeepEqual(expected[i], actual[i]):
+			fmt.Printf("Call %d differs:\n  expected: %s\n  actual:   %s\n", i, formatReplayCall(expected[i]), formatReplayCall(actual[i]))
+		default:
+			continue
+		}
+		matches = false
+	}
+	return matches
+}
+
+// formatReplayCall formats a call as a line of JSON
+func formatReplayCall(call replayCall) string {
+	line, _ := json.Marshal(call)
+	return string(line)
+}
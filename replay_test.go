@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"slack-rag-server/src/fakeslack"
+	"slack-rag-server/src/services"
+)
+
+// TestReplay replays a recorded mention, direct message and slash command with canned answers
+// and checks the calls the bot made against testdata/replay.expected.json. After an intended
+// change in what the bot sends, regenerate the expected calls with
+//
+//	go run . replay -answers testdata/answers.json -expect testdata/replay.expected.json -update testdata/replay.jsonl
+func TestReplay(t *testing.T) {
+	t.Setenv("RAGBOT_BACKEND", services.BackendFake)
+	t.Setenv("RAGBOT_FAKE_ANSWERS_FILE", "testdata/answers.json")
+
+	// Keep the settings of the machine running the tests out of the replay
+	for _, name := range []string{
+		"RAGBOT_ACTIONS_FILE",
+		"RAGBOT_AUDIT_LOG",
+		"RAGBOT_CANDIDATE_ALIAS",
+		"RAGBOT_EXPERIMENT_LOG",
+		"RAGBOT_GUARDRAIL_ID",
+		"RAGBOT_KB_FILTERS_FILE",
+		"RAGBOT_MAINTAINERS",
+		"RAGBOT_RECORD_FILE",
+		"RAGBOT_REDACTION_FILE",
+		"RAGBOT_SESSIONS_FILE",
+		"RAGBOT_SYNC_SCHEDULES_FILE",
+		"RAGBOT_UPLOAD_THRESHOLD",
+		"RAGBOT_WAKE_PHRASES",
+	} {
+		t.Setenv(name, "")
+	}
+
+	requests, err := loadRecording("testdata/replay.jsonl")
+	if err != nil {
+		t.Fatalf("Failed to load recording: %v", err)
+	}
+
+	calls, err := replayRequests(requests, fakeslack.DefaultBotUserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := readReplayCalls("testdata/replay.expected.json")
+	if err != nil {
+		t.Fatalf("Failed to read expected calls: %v", err)
+	}
+	if !compareReplayCalls(expected, calls) {
+		t.Errorf("The replayed calls differ from testdata/replay.expected.json")
+	}
+}
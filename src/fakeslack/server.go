@@ -0,0 +1,304 @@
+// Package fakeslack is a fake Slack Web API that records the calls the bot makes, for
+// replaying recorded traffic and developing the bot without a Slack workspace
+package fakeslack
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fake identities returned by auth.test
+const (
+	DefaultBotUserID = "U0RAGBOT"
+	TeamID           = "T0FAKE"
+)
+
+// ResponseURLPath is the path prefix of the fake response URLs slash commands and
+// interactions respond to
+const ResponseURLPath = "/response/"
+
+// UploadURLPath is the path prefix of the fake URLs file contents are uploaded to, handed out
+// by files.getUploadURLExternal
+const UploadURLPath = "/upload/"
+
+// UserMessageMethod is the method of the calls recording messages sent by simulated users
+const UserMessageMethod = "user.message"
+
+// Call is a Slack Web API call or response URL post made by the bot. Params holds the form
+// fields of the call, or the top-level fields of a JSON body with non-string values encoded as JSON.
+// files.completeUploadExternal calls also hold the channel, filename and text of the file shared.
+type Call struct {
+	Method string            `json:"method"`
+	Params map[string]string `json:"params"`
+	TS     string            `json:"ts,omitempty"`
+	Time   time.Time         `json:"time"`
+}
+
+// Server serves the Slack Web API methods the bot uses under /api/, response URLs under
+// ResponseURLPath and file upload URLs under UploadURLPath. Calls that change what users see are answered with plausible responses;
+// lookups return minimal users, channels and empty threads.
+type Server struct {
+	// BotUserID is the user ID of the bot returned by auth.test
+	BotUserID string
+
+	mu      sync.Mutex
+	calls   []Call
+	lastTS  int64
+	uploads map[string]upload
+}
+
+// upload is a file uploaded to an upload URL and not shared yet
+type upload struct {
+	filename string
+	content  string
+}
+
+// New creates a new Server
+func New() *Server {
+	return &Server{BotUserID: DefaultBotUserID, uploads: map[string]upload{}}
+}
+
+// ServeHTTP records a call and answers it
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params, err := requestParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, ResponseURLPath) {
+		params["response_url"] = r.URL.Path
+		s.record("response_url", params, "")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, UploadURLPath) {
+		s.saveUpload(strings.TrimPrefix(r.URL.Path, UploadURLPath), params["content"])
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	method := strings.TrimPrefix(r.URL.Path, "/api/")
+	ts := ""
+	switch method {
+	case "chat.postMessage", "chat.postEphemeral", "files.getUploadURLExternal":
+		ts = s.nextTS()
+	case "chat.update":
+		ts = params["ts"]
+	case "files.completeUploadExternal":
+		s.shareUploads(params)
+	}
+	s.record(method, params, ts)
+
+	reply := s.response(method, params, ts)
+	if fileID, ok := reply["file_id"].(string); ok {
+		reply["upload_url"] = s.newUpload(r, fileID, params["filename"])
+	}
+	writeJSON(w, reply)
+}
+
+// newUpload expects the content of a file and returns the URL to upload it to, on the same
+// host and under the same prefix the server is mounted at as the API call asking for it
+func (s *Server) newUpload(r *http.Request, fileID, filename string) string {
+	s.mu.Lock()
+	s.uploads[fileID] = upload{filename: filename}
+	s.mu.Unlock()
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	requestPath, _, _ := strings.Cut(r.RequestURI, "?")
+	prefix := strings.TrimSuffix(requestPath, r.URL.Path)
+	return scheme + "://" + r.Host + prefix + UploadURLPath + fileID
+}
+
+// saveUpload stores the content uploaded for a file
+func (s *Server) saveUpload(fileID, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file := s.uploads[fileID]
+	file.content = content
+	s.uploads[fileID] = file
+}
+
+// shareUploads adds the channel, filename and text of the files a files.completeUploadExternal
+// call shares to its parameters
+func (s *Server) shareUploads(params map[string]string) {
+	var files []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(params["files"]), &files); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	params["channel"] = params["channel_id"]
+	for _, file := range files {
+		if shared, ok := s.uploads[file.ID]; ok {
+			params["filename"] = shared.filename
+			params["text"] = shared.content
+			delete(s.uploads, file.ID)
+		}
+	}
+}
+
+// requestParams reads the parameters of a form-encoded or JSON request
+func requestParams(r *http.Request) (map[string]string, error) {
+	params := map[string]string{}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		if len(body) == 0 {
+			return params, nil
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return nil, err
+		}
+		for key, value := range fields {
+			var text string
+			if err := json.Unmarshal(value, &text); err == nil {
+				params[key] = text
+			} else {
+				params[key] = string(value)
+			}
+		}
+		return params, nil
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+		return nil, err
+	}
+	for key, values := range r.Form {
+		if len(values) > 0 {
+			params[key] = values[0]
+		}
+	}
+	return params, nil
+}
+
+// response returns the reply to a Slack Web API method
+func (s *Server) response(method string, params map[string]string, ts string) map[string]interface{} {
+	reply := map[string]interface{}{"ok": true}
+
+	switch method {
+	case "auth.test":
+		reply["user_id"] = s.BotUserID
+		reply["user"] = "ragbot"
+		reply["team_id"] = TeamID
+		reply["team"] = "Fake Workspace"
+		reply["url"] = "https://fake.slack.com/"
+	case "chat.postMessage", "chat.update":
+		reply["channel"] = params["channel"]
+		reply["ts"] = ts
+		reply["message"] = map[string]interface{}{"text": params["text"], "ts": ts, "user": s.BotUserID}
+	case "chat.postEphemeral":
+		reply["message_ts"] = ts
+	case "users.info":
+		user := params["user"]
+		reply["user"] = map[string]interface{}{
+			"id":        user,
+			"name":      strings.ToLower(user),
+			"real_name": "User " + user,
+			"tz":        "UTC",
+			"profile": map[string]interface{}{
+				"real_name":    "User " + user,
+				"display_name": strings.ToLower(user),
+				"email":        strings.ToLower(user) + "@example.com",
+			},
+		}
+	case "conversations.info":
+		channel := params["channel"]
+		reply["channel"] = map[string]interface{}{
+			"id":    channel,
+			"name":  strings.ToLower(channel),
+			"is_im": strings.HasPrefix(channel, "D"),
+		}
+	case "conversations.replies":
+		reply["messages"] = []interface{}{}
+		reply["has_more"] = false
+	case "usergroups.users.list":
+		reply["users"] = []string{}
+	case "views.publish", "views.open", "views.update":
+		reply["view"] = map[string]interface{}{"id": "V0FAKE"}
+	case "files.getUploadURLExternal":
+		reply["file_id"] = "F" + strings.ReplaceAll(ts, ".", "")
+	case "files.completeUploadExternal":
+		var files []map[string]interface{}
+		json.Unmarshal([]byte(params["files"]), &files)
+		reply["files"] = files
+	}
+
+	return reply
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// nextTS returns a new message timestamp, increasing with every message
+func (s *Server) nextTS() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UnixMicro()
+	if now <= s.lastTS {
+		now = s.lastTS + 1
+	}
+	s.lastTS = now
+	return fmt.Sprintf("%d.%06d", now/1e6, now%1e6)
+}
+
+// record stores a call
+func (s *Server) record(method string, params map[string]string, ts string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = append(s.calls, Call{Method: method, Params: params, TS: ts, Time: time.Now()})
+}
+
+// UserMessage records a message a simulated user sent and returns it with its new timestamp
+func (s *Server) UserMessage(channel, user, text, threadTS string) Call {
+	ts := s.nextTS()
+	params := map[string]string{"channel": channel, "user": user, "text": text}
+	if threadTS != "" {
+		params["thread_ts"] = threadTS
+	}
+	s.record(UserMessageMethod, params, ts)
+
+	return Call{Method: UserMessageMethod, Params: params, TS: ts}
+}
+
+// Calls returns the calls recorded so far
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Call{}, s.calls...)
+}
+
+// Reset forgets the recorded calls
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = nil
+}
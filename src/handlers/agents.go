@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+
+	"slack-rag-server/src/services"
+	"slack-rag-server/src/types"
+	"slack-rag-server/src/utils"
+)
+
+// Action IDs of the buttons that confirm agent changes
+const (
+	ActionAgentPrepare = "agent_prepare"
+	ActionAgentPromote = "agent_promote"
+)
+
+// agentMonitorMinutes is how long agent and alias preparation is followed before giving up
+const agentMonitorMinutes = 10
+
+// HandleAgentVersions handles the /ragbot-agent versions command
+func (h *CommandHandler) HandleAgentVersions(cmd slack.SlashCommand, args commandArgs) {
+	utils.LogInfo(fmt.Sprintf("Processing /ragbot-agent versions command"))
+
+	response, err := h.bedrockService.ListAgentVersions()
+	if err != nil {
+		utils.LogError(err, "Error in /ragbot-agent versions")
+		h.respondToCommand(cmd, "Error listing agent versions: "+err.Error())
+		return
+	}
+
+	// Check if response contains an error
+	if errorResp, ok := response.(types.ErrorResponse); ok {
+		utils.LogError(fmt.Errorf(errorResp.Error), "Error in /ragbot-agent versions")
+		h.respondToCommand(cmd, "Error listing agent versions: "+errorResp.Error)
+		return
+	}
+
+	versions, ok := response.(types.AgentVersionList)
+	if !ok {
+		h.respondToCommand(cmd, fmt.Sprintf("%v", response))
+		return
+	}
+
+	// Show which aliases serve each version; the list is still useful without them
+	servedBy := map[string][]string{}
+	if response, err := h.bedrockService.ListAgentAliases(); err == nil {
+		if aliases, ok := response.(types.AgentAliasList); ok {
+			for _, alias := range aliases.Aliases {
+				for _, version := range alias.Versions {
+					servedBy[version] = append(servedBy[version], "`"+alias.Name+"`")
+				}
+			}
+		}
+	}
+
+	blocks := []slack.Block{titleBlock("🧬", fmt.Sprintf("Agent versions (%d)", versions.Count))}
+	for i, version := range versions.Versions {
+		if !fitsMessage(blocks, 3) {
+			blocks = append(blocks, contextBlock(fmt.Sprintf("%d older versions not shown.", len(versions.Versions)-i)))
+			break
+		}
+
+		details := []string{fmt.Sprintf("Status: %s", version.Status), "Updated " + utils.SlackDate(version.UpdatedAt)}
+		if aliases := servedBy[version.Version]; len(aliases) > 0 {
+			details = append(details, "Served by "+strings.Join(aliases, ", "))
+		}
+
+		text := fmt.Sprintf("%s *Version %s*", statusEmoji(version.Status), version.Version)
+		if version.Description != "" {
+			text += "\n" + version.Description
+		}
+		blocks = append(blocks,
+			slack.NewDividerBlock(),
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+			contextBlock(strings.Join(details, " · ")),
+		)
+	}
+
+	h.respondToCommandWithBlocks(cmd, fmt.Sprintf("%d agent versions", versions.Count), blocks)
+}
+
+// HandleAgentAliases handles the /ragbot-agent aliases command
+func (h *CommandHandler) HandleAgentAliases(cmd slack.SlashCommand, args commandArgs) {
+	utils.LogInfo(fmt.Sprintf("Processing /ragbot-agent aliases command"))
+
+	response, err := h.bedrockService.ListAgentAliases()
+	if err != nil {
+		utils.LogError(err, "Error in /ragbot-agent aliases")
+		h.respondToCommand(cmd, "Error listing agent aliases: "+err.Error())
+		return
+	}
+
+	// Check if response contains an error
+	if errorResp, ok := response.(types.ErrorResponse); ok {
+		utils.LogError(fmt.Errorf(errorResp.Error), "Error in /ragbot-agent aliases")
+		h.respondToCommand(cmd, "Error listing agent aliases: "+errorResp.Error)
+		return
+	}
+
+	aliases, ok := response.(types.AgentAliasList)
+	if !ok {
+		h.respondToCommand(cmd, fmt.Sprintf("%v", response))
+		return
+	}
+
+	blocks := []slack.Block{titleBlock("🏷️", fmt.Sprintf("Agent aliases (%d)", aliases.Count))}
+	for i, alias := range aliases.Aliases {
+		if !fitsMessage(blocks, 3) {
+			blocks = append(blocks, contextBlock(fmt.Sprintf("%d more aliases not shown.", len(aliases.Aliases)-i)))
+			break
+		}
+
+		versions := "none"
+		if len(alias.Versions) > 0 {
+			versions = strings.Join(alias.Versions, ", ")
+		}
+
+		text := fmt.Sprintf("%s *%s* `%s`", statusEmoji(alias.Status), alias.Name, alias.AliasID)
+		if alias.Description != "" {
+			text += "\n" + alias.Description
+		}
+		blocks = append(blocks,
+			slack.NewDividerBlock(),
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+			contextBlock(fmt.Sprintf("Status: %s · Version: %s · Updated %s", alias.Status, versions, utils.SlackDate(alias.UpdatedAt))),
+		)
+	}
+
+	h.respondToCommandWithBlocks(cmd, fmt.Sprintf("%d agent aliases", aliases.Count), blocks)
+}
+
+// HandleAgentPrepare handles the /ragbot-agent prepare command by asking for confirmation
+func (h *CommandHandler) HandleAgentPrepare(cmd slack.SlashCommand, args commandArgs) {
+	utils.LogInfo(fmt.Sprintf("Processing /ragbot-agent prepare command from user %s", cmd.UserID))
+
+	confirm := slack.NewConfirmationBlockObject(
+		slack.NewTextBlockObject(slack.PlainTextType, "Prepare agent?", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "The DRAFT version will be rebuilt with the agent's latest configuration.", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "Prepare", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+	)
+
+	blocks := []slack.Block{
+		titleBlock("🛠️", "Prepare agent"),
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, "Preparing rebuilds the DRAFT version of the agent with its latest configuration. Aliases keep serving their current versions until they are promoted.", false, false),
+			nil,
+			nil,
+		),
+		slack.NewActionBlock("agent_prepare_actions",
+			slack.NewButtonBlockElement(ActionAgentPrepare, services.DraftAgentVersion,
+				slack.NewTextBlockObject(slack.PlainTextType, "Prepare agent", true, false)).WithStyle(slack.StylePrimary).WithConfirm(confirm),
+		),
+	}
+
+	h.respondToCommandWithBlocks(cmd, "Prepare the agent?", blocks)
+}
+
+// HandleAgentPromote handles the /ragbot-agent promote command by asking for confirmation
+func (h *CommandHandler) HandleAgentPromote(cmd slack.SlashCommand, args commandArgs) {
+	utils.LogInfo(fmt.Sprintf("Processing /ragbot-agent promote command from user %s", cmd.UserID))
+
+	alias, err := h.bedrockService.FindAgentAlias(args.Arg("alias"))
+	if err != nil {
+		h.respondToCommand(cmd, "Error: "+err.Error())
+		return
+	}
+
+	version := args.Arg("version")
+	target := "a new version created from DRAFT"
+	if version != "" {
+		target = "version " + version
+	}
+
+	current := "no version"
+	if len(alias.Versions) > 0 {
+		current = "version " + strings.Join(alias.Versions, ", ")
+	}
+
+	question := fmt.Sprintf("Point alias %s (%s) from %s to %s?", alias.Name, alias.AliasID, current, target)
+	confirm := slack.NewConfirmationBlockObject(
+		slack.NewTextBlockObject(slack.PlainTextType, "Promote alias?", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, question+" Questions sent to this alias will be answered by the new version.", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "Promote", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+	)
+
+	blocks := []slack.Block{
+		titleBlock("🚀", "Promote agent alias"),
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, question, false, false), nil, nil),
+		slack.NewActionBlock("agent_promote_actions",
+			slack.NewButtonBlockElement(ActionAgentPromote, alias.AliasID+":"+version,
+				slack.NewTextBlockObject(slack.PlainTextType, "Promote alias", true, false)).WithStyle(slack.StyleDanger).WithConfirm(confirm),
+		),
+	}
+
+	h.respondToCommandWithBlocks(cmd, fmt.Sprintf("Promote alias %s to %s?", alias.Name, target), blocks)
+}
+
+// HandleAgentAction runs a confirmed agent change and follows it until the agent or alias is
+// ready. The caller checks that the user is a maintainer.
+func (h *CommandHandler) HandleAgentAction(callback slack.InteractionCallback, action *slack.BlockAction) {
+	cmd := slack.SlashCommand{
+		Command:     CommandName + " agent",
+		UserID:      callback.User.ID,
+		ChannelID:   callback.Channel.ID,
+		ResponseURL: callback.ResponseURL,
+	}
+
+	// Agent changes affect everyone, so the outcome is posted to the channel
+	h.setResponseType(cmd, true)
+	defer h.clearResponseType(cmd)
+
+	switch action.ActionID {
+	case ActionAgentPrepare:
+		h.prepareAgent(cmd)
+	case ActionAgentPromote:
+		aliasID, version, ok := strings.Cut(action.Value, ":")
+		if !ok {
+			utils.LogWarning("Invalid agent promote value: " + action.Value)
+			return
+		}
+		h.promoteAgentAlias(cmd, aliasID, version)
+	}
+}
+
+// prepareAgent prepares the agent and reports when it is ready
+func (h *CommandHandler) prepareAgent(cmd slack.SlashCommand) {
+	response, err := h.bedrockService.PrepareAgent()
+	if err != nil {
+		utils.LogError(err, "Error preparing agent")
+		h.respondToCommand(cmd, "Error preparing agent: "+err.Error())
+		return
+	}
+	if errorResp, ok := response.(types.ErrorResponse); ok {
+		utils.LogError(fmt.Errorf(errorResp.Error), "Error preparing agent")
+		h.respondToCommand(cmd, "Error preparing agent: "+errorResp.Error)
+		return
+	}
+
+	utils.LogInfo(fmt.Sprintf("Agent preparation started by user %s", cmd.UserID))
+	h.respondToCommandWithBlocks(cmd, "Preparing agent", []slack.Block{
+		titleBlock("⏳", "Preparing agent"),
+		contextBlock(fmt.Sprintf("Started by <@%s>. A message will follow when the agent is ready.", cmd.UserID)),
+	})
+
+	response, err = h.bedrockService.MonitorAgentPreparation(agentMonitorMinutes)
+	h.respondWithAgentPreparation(cmd, "Agent preparation", response, err)
+}
+
+// promoteAgentAlias points an alias at a version and reports when the alias is ready
+func (h *CommandHandler) promoteAgentAlias(cmd slack.SlashCommand, aliasID, version string) {
+	response, err := h.bedrockService.UpdateAgentAlias(aliasID, version)
+	if err != nil {
+		utils.LogError(err, "Error updating agent alias")
+		h.respondToCommand(cmd, "Error updating agent alias: "+err.Error())
+		return
+	}
+	if errorResp, ok := response.(types.ErrorResponse); ok {
+		utils.LogError(fmt.Errorf(errorResp.Error), "Error updating agent alias")
+		h.respondToCommand(cmd, "Error updating agent alias: "+errorResp.Error)
+		return
+	}
+
+	utils.LogInfo(fmt.Sprintf("Alias %s promoted to version %q by user %s", aliasID, version, cmd.UserID))
+	h.respondToCommandWithBlocks(cmd, "Updating agent alias", []slack.Block{
+		titleBlock("⏳", "Updating agent alias `"+aliasID+"`"),
+		contextBlock(fmt.Sprintf("Started by <@%s>. A message will follow when the alias is ready.", cmd.UserID)),
+	})
+
+	response, err = h.bedrockService.MonitorAgentAlias(aliasID, agentMonitorMinutes)
+	h.respondWithAgentPreparation(cmd, "Alias update", response, err)
+}
+
+// respondWithAgentPreparation reports the outcome of preparing the agent or updating an alias
+func (h *CommandHandler) respondWithAgentPreparation(cmd slack.SlashCommand, title string, response interface{}, err error) {
+	if err != nil {
+		utils.LogError(err, "Error monitoring "+strings.ToLower(title))
+		h.respondToCommand(cmd, title+" could not be monitored: "+err.Error())
+		return
+	}
+	if errorResp, ok := response.(types.ErrorResponse); ok {
+		h.respondToCommand(cmd, title+" could not be monitored: "+errorResp.Error)
+		return
+	}
+
+	preparation, ok := response.(types.AgentPreparation)
+	if !ok {
+		h.respondToCommand(cmd, fmt.Sprintf("%v", response))
+		return
+	}
+
+	emoji := "✅"
+	if !preparation.Success {
+		emoji = "❌"
+	}
+
+	fields := []blockField{
+		{"Agent ID", preparation.AgentID},
+		{"Status", preparation.Status},
+		{"Version", preparation.Version},
+		{"Finished At", utils.SlackDate(preparation.PreparedAt)},
+	}
+	if preparation.AliasID != "" {
+		fields = append([]blockField{{"Alias ID", preparation.AliasID}}, fields...)
+	}
+
+	blocks := []slack.Block{titleBlock(emoji, title+" finished")}
+	blocks = append(blocks, fieldBlocks(fields)...)
+	blocks = append(blocks, contextBlock(preparation.Message))
+
+	h.respondToCommandWithBlocks(cmd, title+" finished: "+preparation.Message, blocks)
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+
+	"slack-rag-server/src/utils"
+)
+
+// maxSectionLength is the longest text Slack accepts in a section block
+const maxSectionLength = 3000
+
+// maxSectionFields is the most fields Slack accepts in a section block
+const maxSectionFields = 10
+
+// maxMessageBlocks is the most blocks Slack accepts in a single message
+const maxMessageBlocks = 50
+
+// blockField is a labelled value shown in the two-column fields layout of a section
+type blockField struct {
+	Label string
+	Value string
+}
+
+// statusEmoji returns an emoji for the status of a Bedrock resource or ingestion job
+func statusEmoji(status string) string {
+	switch strings.ToUpper(status) {
+	case "COMPLETE", "ACTIVE", "AVAILABLE", "PREPARED", "HEALTHY":
+		return "✅"
+	case "FAILED", "DELETE_UNSUCCESSFUL", "NOT_PREPARED", "UNHEALTHY":
+		return "❌"
+	case "STOPPED", "STOPPING":
+		return "⏹️"
+	case "DELETING":
+		return "🗑️"
+	case "":
+		return "ℹ️"
+	default:
+		return "⏳"
+	}
+}
+
+// titleBlock renders the bold title of a command response, led by a status emoji
+func titleBlock(emoji, title string) slack.Block {
+	return slack.NewSectionBlock(
+		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s *%s*", emoji, title), false, false),
+		nil,
+		nil,
+	)
+}
+
+// fieldBlocks renders labelled values two columns wide, in as many sections as needed
+func fieldBlocks(fields []blockField) []slack.Block {
+	var blocks []slack.Block
+	for start := 0; start < len(fields); start += maxSectionFields {
+		end := start + maxSectionFields
+		if end > len(fields) {
+			end = len(fields)
+		}
+
+		var objects []*slack.TextBlockObject
+		for _, field := range fields[start:end] {
+			value := field.Value
+			if value == "" {
+				value = "-"
+			}
+			objects = append(objects, slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*%s*\n%s", field.Label, value), false, false))
+		}
+		blocks = append(blocks, slack.NewSectionBlock(nil, objects, nil))
+	}
+	return blocks
+}
+
+// textBlocks renders markdown text as sections, splitting it on line breaks to stay within
+// Slack's section length limit
+func textBlocks(text string) []slack.Block {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+
+	var blocks []slack.Block
+	var chunk []string
+	length := 0
+
+	flush := func() {
+		if len(chunk) > 0 {
+			blocks = append(blocks, slack.NewSectionBlock(
+				slack.NewTextBlockObject(slack.MarkdownType, strings.Join(chunk, "\n"), false, false),
+				nil,
+				nil,
+			))
+		}
+		chunk, length = nil, 0
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if len(line) > maxSectionLength {
+			line = utils.TruncateText(line, maxSectionLength-1)
+		}
+		if length+len(line)+1 > maxSectionLength {
+			flush()
+		}
+		chunk = append(chunk, line)
+		length += len(line) + 1
+	}
+	flush()
+
+	return blocks
+}
+
+// fitsMessage reports whether n more blocks fit in a message after the given blocks, keeping
+// room for a closing note about anything left out
+func fitsMessage(blocks []slack.Block, n int) bool {
+	return len(blocks)+n+1 <= maxMessageBlocks
+}
+
+// contextBlock renders a line of small print
+func contextBlock(text string) slack.Block {
+	return slack.NewContextBlock("",
+		slack.NewTextBlockObject(slack.MarkdownType, text, false, false),
+	)
+}
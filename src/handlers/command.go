@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/slack-go/slack"
 
@@ -18,21 +20,48 @@ import (
 type CommandHandler struct {
 	api            *slack.Client
 	bedrockService *services.BedrockService
+	filters        *services.FilterPolicy
+	sessions       *services.SessionStore
+	history        *services.HistoryStore
+	scheduler      *services.SyncScheduler
+	experiment     *services.AliasExperiment
+	commands       []commandSpec
+
+	// Response types of running commands by response URL, see setResponseType
+	responseTypesMu sync.Mutex
+	responseTypes   map[string]string
+
+	// Pagination tokens of "Older jobs" buttons, see savePageToken
+	pageTokens *pageTokenStore
 }
 
 // NewCommandHandler creates a new CommandHandler
-func NewCommandHandler(api *slack.Client, bedrockService *services.BedrockService) *CommandHandler {
-	return &CommandHandler{
+func NewCommandHandler(api *slack.Client, bedrockService *services.BedrockService, filters *services.FilterPolicy, sessions *services.SessionStore, history *services.HistoryStore, scheduler *services.SyncScheduler, experiment *services.AliasExperiment) *CommandHandler {
+	h := &CommandHandler{
 		api:            api,
 		bedrockService: bedrockService,
+		filters:        filters,
+		sessions:       sessions,
+		history:        history,
+		scheduler:      scheduler,
+		experiment:     experiment,
+		responseTypes:  map[string]string{},
+		pageTokens:     newPageTokenStore(),
 	}
+	h.commands = h.commandSpecs()
+	return h
 }
 
 // HandleGetDataSource handles the /ragbot-get-datasource command
-func (h *CommandHandler) HandleGetDataSource(cmd slack.SlashCommand) {
+func (h *CommandHandler) HandleGetDataSource(cmd slack.SlashCommand, args commandArgs) {
 	utils.LogInfo(fmt.Sprintf("Processing /ragbot-get-datasource command"))
 
-	response, err := h.bedrockService.GetDataSource()
+	dataSourceID, ok := h.resolveDataSource(cmd, args.Arg("data source"))
+	if !ok {
+		return
+	}
+
+	response, err := h.bedrockService.GetDataSource(dataSourceID)
 	if err != nil {
 		utils.LogError(err, "Error in /ragbot-get-datasource")
 		h.respondToCommand(cmd, "Error getting data source information: "+err.Error())
@@ -46,51 +75,54 @@ func (h *CommandHandler) HandleGetDataSource(cmd slack.SlashCommand) {
 		return
 	}
 
-	var formattedResponse string
-
 	// Check if response indicates no jobs found
 	if noJobsResp, ok := response.(struct {
 		Status  string `json:"status"`
 		Message string `json:"message"`
 	}); ok && noJobsResp.Status == "NO_JOBS_FOUND" {
-		formattedResponse = noJobsResp.Message
-	} else if dsInfo, ok := response.(types.DataSourceInfo); ok {
-		formattedResponse = fmt.Sprintf(
-			"Data Source: %s\nKnowledge Base: %s\nMessage: %s\nStatus: %s\nLast sync: %s",
-			dsInfo.DataSourceID,
-			dsInfo.KnowledgeBaseID,
-			dsInfo.Description,
-			dsInfo.Status,
-			utils.FormatDate(dsInfo.UpdatedAt),
-		)
-	} else {
-		formattedResponse = fmt.Sprintf("%v", response)
+		h.respondToCommand(cmd, noJobsResp.Message)
+		return
+	}
+
+	dsInfo, ok := response.(types.DataSourceInfo)
+	if !ok {
+		h.respondToCommand(cmd, fmt.Sprintf("%v", response))
+		return
+	}
+
+	blocks := []slack.Block{titleBlock(statusEmoji(dsInfo.Status), "Data source information")}
+	blocks = append(blocks, fieldBlocks([]blockField{
+		{"Data Source", dsInfo.DataSourceID},
+		{"Knowledge Base", dsInfo.KnowledgeBaseID},
+		{"Last Job Status", dsInfo.Status},
+		{"Last Sync", utils.SlackDate(dsInfo.UpdatedAt)},
+	})...)
+	if dsInfo.Description != "" {
+		blocks = append(blocks, contextBlock(dsInfo.Description))
 	}
 
-	h.respondToCommand(cmd, "DATA SOURCE INFORMATION:\n\n"+formattedResponse)
+	h.respondToCommandWithBlocks(cmd, "Data source "+dsInfo.DataSourceID+": "+dsInfo.Status, blocks)
 }
 
 // HandleSyncDataSource handles the /ragbot-sync-datasource command
-func (h *CommandHandler) HandleSyncDataSource(cmd slack.SlashCommand) {
+func (h *CommandHandler) HandleSyncDataSource(cmd slack.SlashCommand, args commandArgs) {
 	utils.LogInfo(fmt.Sprintf("Processing /ragbot-sync-datasource command from user %s", cmd.UserID))
 
-	// Check user permissions - disabled for now as Go doesn't have direct role checks
-	// Uncomment and implement when needed
-	/*
-	hasRequiredRole, err := utils.CheckUserRole(h.api, cmd.UserID, "aws-bot-maintainer")
-	if err != nil {
-		utils.LogError(err, "Error checking user role")
-		h.respondToCommand(cmd, "Error checking permissions: "+err.Error())
+	if args.Has("all") {
+		if args.Arg("data source") != "" {
+			h.respondToCommand(cmd, "Pass either a data source or --all, not both.")
+			return
+		}
+		h.syncAllDataSources(cmd)
 		return
 	}
 
-	if !hasRequiredRole {
-		h.respondToCommand(cmd, "Sorry, you do not have permission to use this command. Only users with the aws-bot-maintainer role can sync the data source.")
+	dataSourceID, ok := h.resolveDataSource(cmd, args.Arg("data source"))
+	if !ok {
 		return
 	}
-	*/
 
-	response, err := h.bedrockService.SyncDataSource()
+	response, err := h.bedrockService.SyncDataSource(dataSourceID, "Manual")
 	if err != nil {
 		utils.LogError(err, "Error in /ragbot-sync-datasource")
 		h.respondToCommand(cmd, "Error syncing data source: "+err.Error())
@@ -104,43 +136,107 @@ func (h *CommandHandler) HandleSyncDataSource(cmd slack.SlashCommand) {
 		return
 	}
 
-	// Format response
-	var formattedResponse string
-	if dsSync, ok := response.(types.DataSourceSync); ok {
-		formattedResponse = fmt.Sprintf(
-			"Data Source: %s\nKnowledge Base: %s\nJob ID: %s\nStatus: %s",
-			dsSync.DataSourceID,
-			dsSync.KnowledgeBaseID,
-			dsSync.IngestionJobID,
-			dsSync.Status,
-		)
-	} else {
-		formattedResponse = fmt.Sprintf("%v", response)
+	dsSync, ok := response.(types.DataSourceSync)
+	if !ok {
+		h.respondToCommand(cmd, fmt.Sprintf("%v", response))
+		return
+	}
+
+	blocks := []slack.Block{titleBlock(statusEmoji(dsSync.Status), "Data source sync started")}
+	blocks = append(blocks, fieldBlocks([]blockField{
+		{"Data Source", dsSync.DataSourceID},
+		{"Knowledge Base", dsSync.KnowledgeBaseID},
+		{"Job ID", "`" + dsSync.IngestionJobID + "`"},
+		{"Status", dsSync.Status},
+	})...)
+	blocks = append(blocks, contextBlock(fmt.Sprintf("Started by <@%s>. Use `%s job-status %s %s` to follow the job.",
+		cmd.UserID, CommandName, dsSync.IngestionJobID, dsSync.DataSourceID)))
+
+	h.respondToCommandWithBlocks(cmd, "Data source sync started: job "+dsSync.IngestionJobID, blocks)
+}
+
+// syncAllDataSources starts a sync of every data source, then monitors the jobs together and
+// posts a combined summary once they have all finished
+func (h *CommandHandler) syncAllDataSources(cmd slack.SlashCommand) {
+	response, err := h.bedrockService.SyncAllDataSources("Manual")
+	if err != nil {
+		utils.LogError(err, "Error in /ragbot-sync-datasource --all")
+		h.respondToCommand(cmd, "Error syncing data sources: "+err.Error())
+		return
+	}
+
+	// Check if response contains an error
+	if errorResp, ok := response.(types.ErrorResponse); ok {
+		utils.LogError(fmt.Errorf(errorResp.Error), "Error in /ragbot-sync-datasource --all")
+		h.respondToCommand(cmd, "Error syncing data sources: "+errorResp.Error)
+		return
+	}
+
+	batch, ok := response.(types.DataSourceSyncBatch)
+	if !ok {
+		h.respondToCommand(cmd, fmt.Sprintf("%v", response))
+		return
+	}
+
+	blocks := append([]slack.Block{titleBlock("🔄", "Data source syncs started")}, textBlocks(formatSyncBatch(batch))...)
+	h.respondToCommandWithBlocks(cmd, fmt.Sprintf("Started %d data source syncs", len(batch.Jobs)), blocks)
+	if len(batch.Jobs) == 0 {
+		return
 	}
 
-	h.respondToCommand(cmd, "DATA SOURCE SYNC INITIATED:\n\n"+formattedResponse)
+	results := h.bedrockService.MonitorIngestionJobs(batch.Jobs, syncAllMonitorMinutes)
+	blocks = append([]slack.Block{titleBlock("🏁", "Data source syncs finished")}, textBlocks(formatSyncResults(batch.Jobs, results))...)
+	h.respondToCommandWithBlocks(cmd, "Data source syncs finished", blocks)
+}
+
+// resolveDataSource resolves a data source name or ID argument (the configured data source
+// when empty), responding with the error when it does not match one
+func (h *CommandHandler) resolveDataSource(cmd slack.SlashCommand, nameOrID string) (string, bool) {
+	dataSourceID, err := h.bedrockService.ResolveDataSource(nameOrID)
+	if err != nil {
+		h.respondToCommand(cmd, "Error: "+err.Error())
+		return "", false
+	}
+	return dataSourceID, true
 }
 
 // HandleHelp handles the /ragbot-help command
-func (h *CommandHandler) HandleHelp(cmd slack.SlashCommand) {
+func (h *CommandHandler) HandleHelp(cmd slack.SlashCommand, args commandArgs) {
 	utils.LogInfo(fmt.Sprintf("Processing /ragbot-help command"))
 
-	helpText := `Available commands:
-    /ragbot-help - Show this help message
-    /ragbot-kb-status - Check the status of the knowledge base
-    /ragbot-sync-datasource - Trigger a sync of the knowledge base
-    /ragbot-list-datasources - List all available data sources
-    /ragbot-ds-config - Get configuration for the data source
-    /ragbot-get-datasource - Get information about the current data source
-    /ragbot-agent-status - Check the status of the agent
-    /ragbot-job-status <job_id> - Check the status of an ingestion job
-    /ragbot-health-check - Check overall health of the Bedrock agent service`
-
-	h.respondToCommand(cmd, helpText)
+	if name := strings.ToLower(args.Arg("command")); name != "" {
+		spec, rest := h.findCommand(strings.Fields(strings.TrimPrefix(name, legacyCommandPrefix)))
+		if spec == nil || len(rest) > 0 {
+			h.respondToCommand(cmd, fmt.Sprintf("Unknown command `%s`. Use `%s help` to see all commands.", name, CommandName))
+			return
+		}
+		h.respondToCommandWithBlocks(cmd, commandUsage(spec), commandHelpBlocks(spec))
+		return
+	}
+
+	var lines []string
+	for i := range h.commands {
+		spec := &h.commands[i]
+		line := fmt.Sprintf("`%s` - %s", commandUsage(spec), spec.Help)
+		if spec.Permission == permissionMaintainer {
+			line += " _(maintainers only)_"
+		}
+		lines = append(lines, line)
+	}
+
+	blocks := []slack.Block{titleBlock("📖", "Available commands")}
+	blocks = append(blocks, textBlocks(strings.Join(lines, "\n"))...)
+	blocks = append(blocks, contextBlock(fmt.Sprintf(
+		"Data sources are given by name or ID and default to the configured one. "+
+			"Responses are only visible to you unless you add `--public`. "+
+			"Every command is also available as `%s<command>`. Use `%s help <command>` for details.",
+		legacyCommandPrefix, CommandName)))
+
+	h.respondToCommandWithBlocks(cmd, "Available commands", blocks)
 }
 
 // HandleKbStatus handles the /ragbot-kb-status command
-func (h *CommandHandler) HandleKbStatus(cmd slack.SlashCommand) {
+func (h *CommandHandler) HandleKbStatus(cmd slack.SlashCommand, args commandArgs) {
 	utils.LogInfo(fmt.Sprintf("Processing /ragbot-kb-status command"))
 
 	response, err := h.bedrockService.GetKnowledgeBaseStatus()
@@ -157,28 +253,33 @@ func (h *CommandHandler) HandleKbStatus(cmd slack.SlashCommand) {
 		return
 	}
 
-	// Format response
-	var formattedResponse string
-	if kbStatus, ok := response.(types.KnowledgeBaseStatus); ok {
-		formattedResponse = fmt.Sprintf(
-			"Knowledge Base: %s\nStatus: %s\nCreated At: %s\nUpdated At: %s",
-			kbStatus.Name,
-			kbStatus.Status,
-			utils.FormatDate(kbStatus.CreatedAt),
-			utils.FormatDate(kbStatus.UpdatedAt),
-		)
-	} else {
-		formattedResponse = fmt.Sprintf("%v", response)
+	kbStatus, ok := response.(types.KnowledgeBaseStatus)
+	if !ok {
+		h.respondToCommand(cmd, fmt.Sprintf("%v", response))
+		return
 	}
 
-	h.respondToCommand(cmd, "KNOWLEDGE BASE STATUS:\n\n"+formattedResponse)
+	blocks := []slack.Block{titleBlock(statusEmoji(kbStatus.Status), "Knowledge base status")}
+	blocks = append(blocks, fieldBlocks([]blockField{
+		{"Knowledge Base", kbStatus.Name},
+		{"Status", kbStatus.Status},
+		{"Created At", utils.SlackDate(kbStatus.CreatedAt)},
+		{"Updated At", utils.SlackDate(kbStatus.UpdatedAt)},
+	})...)
+
+	h.respondToCommandWithBlocks(cmd, "Knowledge base "+kbStatus.Name+": "+kbStatus.Status, blocks)
 }
 
 // HandleDsConfig handles the /ragbot-ds-config command
-func (h *CommandHandler) HandleDsConfig(cmd slack.SlashCommand) {
+func (h *CommandHandler) HandleDsConfig(cmd slack.SlashCommand, args commandArgs) {
 	utils.LogInfo(fmt.Sprintf("Processing /ragbot-ds-config command"))
 
-	response, err := h.bedrockService.GetDataSourceConfig()
+	dataSourceID, ok := h.resolveDataSource(cmd, args.Arg("data source"))
+	if !ok {
+		return
+	}
+
+	response, err := h.bedrockService.GetDataSourceConfig(dataSourceID)
 	if err != nil {
 		utils.LogError(err, "Error in /ragbot-ds-config")
 		h.respondToCommand(cmd, "Error getting data source configuration: "+err.Error())
@@ -192,26 +293,27 @@ func (h *CommandHandler) HandleDsConfig(cmd slack.SlashCommand) {
 		return
 	}
 
-	// Format response
-	var formattedResponse string
-	if dsConfig, ok := response.(types.DataSourceConfig); ok {
-		formattedResponse = fmt.Sprintf(
-			"Data Source: %s\nStatus: %s\nConfiguration: Type: %s\nCreated At: %s\nUpdated At: %s",
-			dsConfig.Name,
-			dsConfig.Status,
-			dsConfig.ConfigurationType,
-			utils.FormatDate(dsConfig.CreatedAt),
-			utils.FormatDate(dsConfig.UpdatedAt),
-		)
-	} else {
-		formattedResponse = fmt.Sprintf("%v", response)
+	dsConfig, ok := response.(types.DataSourceConfig)
+	if !ok {
+		h.respondToCommand(cmd, fmt.Sprintf("%v", response))
+		return
 	}
 
-	h.respondToCommand(cmd, "DATA SOURCE CONFIGURATION:\n\n"+formattedResponse)
+	blocks := []slack.Block{titleBlock(statusEmoji(dsConfig.Status), "Data source configuration")}
+	blocks = append(blocks, fieldBlocks([]blockField{
+		{"Data Source", dsConfig.Name},
+		{"Status", dsConfig.Status},
+		{"Type", dsConfig.ConfigurationType},
+		{"Data Source ID", dataSourceID},
+		{"Created At", utils.SlackDate(dsConfig.CreatedAt)},
+		{"Updated At", utils.SlackDate(dsConfig.UpdatedAt)},
+	})...)
+
+	h.respondToCommandWithBlocks(cmd, "Data source "+dsConfig.Name+": "+dsConfig.Status, blocks)
 }
 
 // HandleAgentStatus handles the /ragbot-agent-status command
-func (h *CommandHandler) HandleAgentStatus(cmd slack.SlashCommand) {
+func (h *CommandHandler) HandleAgentStatus(cmd slack.SlashCommand, args commandArgs) {
 	utils.LogInfo(fmt.Sprintf("Processing /ragbot-agent-status command"))
 
 	response, err := h.bedrockService.GetAgentStatus()
@@ -228,27 +330,27 @@ func (h *CommandHandler) HandleAgentStatus(cmd slack.SlashCommand) {
 		return
 	}
 
-	// Format response
-	var formattedResponse string
-	if agentStatus, ok := response.(types.AgentStatus); ok {
-		formattedResponse = fmt.Sprintf(
-			"Agent Name: %s\nAgent ID: %s\nStatus: %s\nFoundation Model: %s\nCreated At: %s\nUpdated At: %s",
-			agentStatus.AgentName,
-			agentStatus.AgentID,
-			agentStatus.AgentStatus,
-			agentStatus.FoundationModel,
-			utils.FormatDate(agentStatus.CreatedAt),
-			utils.FormatDate(agentStatus.UpdatedAt),
-		)
-	} else {
-		formattedResponse = fmt.Sprintf("%v", response)
+	agentStatus, ok := response.(types.AgentStatus)
+	if !ok {
+		h.respondToCommand(cmd, fmt.Sprintf("%v", response))
+		return
 	}
 
-	h.respondToCommand(cmd, "AGENT INFORMATION:\n\n"+formattedResponse)
+	blocks := []slack.Block{titleBlock(statusEmoji(agentStatus.AgentStatus), "Agent information")}
+	blocks = append(blocks, fieldBlocks([]blockField{
+		{"Agent Name", agentStatus.AgentName},
+		{"Agent ID", agentStatus.AgentID},
+		{"Status", agentStatus.AgentStatus},
+		{"Foundation Model", agentStatus.FoundationModel},
+		{"Created At", utils.SlackDate(agentStatus.CreatedAt)},
+		{"Updated At", utils.SlackDate(agentStatus.UpdatedAt)},
+	})...)
+
+	h.respondToCommandWithBlocks(cmd, "Agent "+agentStatus.AgentName+": "+agentStatus.AgentStatus, blocks)
 }
 
 // HandleListDataSources handles the /ragbot-list-datasources command
-func (h *CommandHandler) HandleListDataSources(cmd slack.SlashCommand) {
+func (h *CommandHandler) HandleListDataSources(cmd slack.SlashCommand, args commandArgs) {
 	utils.LogInfo(fmt.Sprintf("Processing /ragbot-list-datasources command"))
 
 	response, err := h.bedrockService.ListDataSources()
@@ -265,38 +367,42 @@ func (h *CommandHandler) HandleListDataSources(cmd slack.SlashCommand) {
 		return
 	}
 
-	// Format response
-	var formattedResponse string
-	if dsList, ok := response.(types.DataSourceList); ok {
-		var parts []string
-		for _, source := range dsList.DataSources {
-			parts = append(parts, fmt.Sprintf(
-				"Data Source: %s\n Name: %s\n Status: %s\n Updated At: %s\n",
-				source.DataSourceID,
-				source.Name,
-				source.Status,
-				utils.FormatDate(source.UpdatedAt),
-			))
-		}
-		formattedResponse = strings.Join(parts, "\n")
-	} else {
-		formattedResponse = fmt.Sprintf("%v", response)
+	dsList, ok := response.(types.DataSourceList)
+	if !ok {
+		h.respondToCommand(cmd, fmt.Sprintf("%v", response))
+		return
 	}
 
-	h.respondToCommand(cmd, "AVAILABLE DATA SOURCES:\n\n"+formattedResponse)
+	blocks := []slack.Block{titleBlock("📚", fmt.Sprintf("Available data sources (%d)", dsList.Count))}
+	for _, source := range dsList.DataSources {
+		blocks = append(blocks,
+			slack.NewDividerBlock(),
+			slack.NewSectionBlock(
+				slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s *%s* `%s`", statusEmoji(source.Status), source.Name, source.DataSourceID), false, false),
+				nil,
+				nil,
+			),
+			contextBlock(fmt.Sprintf("Status: %s · Updated %s", source.Status, utils.SlackDate(source.UpdatedAt))),
+		)
+	}
+	if len(dsList.DataSources) == 0 {
+		blocks = append(blocks, contextBlock("The knowledge base has no data sources."))
+	}
+
+	h.respondToCommandWithBlocks(cmd, fmt.Sprintf("%d data sources", dsList.Count), blocks)
 }
 
 // HandleJobStatus handles the /ragbot-job-status command
-func (h *CommandHandler) HandleJobStatus(cmd slack.SlashCommand) {
+func (h *CommandHandler) HandleJobStatus(cmd slack.SlashCommand, args commandArgs) {
 	utils.LogInfo(fmt.Sprintf("Processing /ragbot-job-status command"))
 
-	jobID := strings.TrimSpace(cmd.Text)
-	if jobID == "" {
-		h.respondToCommand(cmd, "Please provide a job ID. Usage: /ragbot-job-status <job_id>")
+	jobID := args.Arg("job_id")
+	dataSourceID, ok := h.resolveDataSource(cmd, args.Arg("data source"))
+	if !ok {
 		return
 	}
 
-	response, err := h.bedrockService.GetIngestionJobStatus(jobID)
+	response, err := h.bedrockService.GetIngestionJobStatus(dataSourceID, jobID)
 	if err != nil {
 		utils.LogError(err, "Error in /ragbot-job-status")
 		h.respondToCommand(cmd, "Error getting job status: "+err.Error())
@@ -310,32 +416,102 @@ func (h *CommandHandler) HandleJobStatus(cmd slack.SlashCommand) {
 		return
 	}
 
-	// Format response
-	var formattedResponse string
-	if jobStatus, ok := response.(types.IngestionJobStatus); ok {
-		failureText := "None"
-		if len(jobStatus.FailureReasons) > 0 {
-			failureText = strings.Join(jobStatus.FailureReasons, "\n")
-		}
+	jobStatus, ok := response.(types.IngestionJobStatus)
+	if !ok {
+		h.respondToCommand(cmd, fmt.Sprintf("%v", response))
+		return
+	}
 
-		formattedResponse = fmt.Sprintf(
-			"Ingestion Job: %s\nStatus: %s\nStarted At: %s\nUpdated At: %s\nStatistics: %s\nFailure Reasons: %s",
-			jobStatus.IngestionJobID,
-			jobStatus.Status,
-			utils.FormatDate(jobStatus.StartedAt),
-			utils.FormatDate(jobStatus.UpdatedAt),
-			jobStatus.Statistics,
-			failureText,
-		)
-	} else {
-		formattedResponse = fmt.Sprintf("%v", response)
+	blocks := []slack.Block{titleBlock(statusEmoji(jobStatus.Status), "Ingestion job status")}
+	blocks = append(blocks, fieldBlocks([]blockField{
+		{"Ingestion Job", "`" + jobStatus.IngestionJobID + "`"},
+		{"Status", jobStatus.Status},
+		{"Started At", utils.SlackDate(jobStatus.StartedAt)},
+		{"Updated At", utils.SlackDate(jobStatus.UpdatedAt)},
+	})...)
+	blocks = append(blocks, textBlocks("*Statistics*\n"+formatIngestionStatistics(jobStatus.Stats))...)
+	blocks = append(blocks, textBlocks("*Failure Reasons*\n"+strings.TrimPrefix(formatIngestionFailures(jobStatus.Failures), "\n"))...)
+
+	h.respondToCommandWithBlocks(cmd, "Ingestion job "+jobStatus.IngestionJobID+": "+jobStatus.Status, blocks)
+}
+
+// HandleJobHistory handles the /ragbot-job-history command
+func (h *CommandHandler) HandleJobHistory(cmd slack.SlashCommand, args commandArgs) {
+	utils.LogInfo(fmt.Sprintf("Processing /ragbot-job-history command"))
+
+	dataSourceID, ok := h.resolveDataSource(cmd, args.Arg("data source"))
+	if !ok {
+		return
+	}
+
+	h.sendJobHistory(cmd, args.Int("n", services.DefaultJobHistorySize), dataSourceID, "")
+}
+
+// sendJobHistory responds with a page of ingestion jobs, continuing from nextToken when set
+func (h *CommandHandler) sendJobHistory(cmd slack.SlashCommand, limit int, dataSourceID, nextToken string) {
+	response, err := h.bedrockService.ListIngestionJobs(dataSourceID, limit, nextToken)
+	if err != nil {
+		utils.LogError(err, "Error in /ragbot-job-history")
+		h.respondToCommand(cmd, "Error listing ingestion jobs: "+err.Error())
+		return
+	}
+
+	// Check if response contains an error
+	if errorResp, ok := response.(types.ErrorResponse); ok {
+		utils.LogError(fmt.Errorf(errorResp.Error), "Error in /ragbot-job-history")
+		h.respondToCommand(cmd, "Error listing ingestion jobs: "+errorResp.Error)
+		return
+	}
+
+	history, ok := response.(types.IngestionJobHistory)
+	if !ok {
+		h.respondToCommand(cmd, fmt.Sprintf("%v", response))
+		return
 	}
 
-	h.respondToCommand(cmd, "INGESTION JOB STATUS:\n\n"+formattedResponse)
+	pageKey := ""
+	if history.NextToken != "" {
+		pageKey = h.pageTokens.save(history.NextToken)
+	}
+
+	h.respondToCommandWithBlocks(
+		cmd,
+		fmt.Sprintf("%d recent ingestion jobs", len(history.Jobs)),
+		jobHistoryBlocks(history, limit, dataSourceID, pageKey),
+	)
+}
+
+// HandleJobHistoryPage shows the next page of ingestion jobs when "Older jobs" is clicked
+func (h *CommandHandler) HandleJobHistoryPage(callback slack.InteractionCallback, value string) {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 {
+		utils.LogWarning("Invalid job history page value: " + value)
+		return
+	}
+	limit, err := strconv.Atoi(parts[0])
+	if err != nil || limit < 1 || limit > services.MaxJobHistorySize {
+		utils.LogWarning("Invalid job history page value: " + value)
+		return
+	}
+
+	cmd := slack.SlashCommand{
+		Command:     "/ragbot-job-history",
+		UserID:      callback.User.ID,
+		ChannelID:   callback.Channel.ID,
+		ResponseURL: callback.ResponseURL,
+	}
+
+	nextToken, ok := h.pageTokens.load(parts[2])
+	if !ok {
+		h.respondToCommand(cmd, fmt.Sprintf("This page of ingestion jobs has expired. Run `/ragbot-job-history %d %s` again.", limit, parts[1]))
+		return
+	}
+
+	h.sendJobHistory(cmd, limit, parts[1], nextToken)
 }
 
 // HandleHealthCheck handles the /ragbot-health-check command
-func (h *CommandHandler) HandleHealthCheck(cmd slack.SlashCommand) {
+func (h *CommandHandler) HandleHealthCheck(cmd slack.SlashCommand, args commandArgs) {
 	utils.LogInfo(fmt.Sprintf("Processing /ragbot-health-check command"))
 
 	healthStatus, err := h.bedrockService.CheckBedrockAgentHealth()
@@ -345,34 +521,163 @@ func (h *CommandHandler) HandleHealthCheck(cmd slack.SlashCommand) {
 		return
 	}
 
-	var responseText string
-	if healthStatus.Healthy {
-		responseText = fmt.Sprintf(
-			"✅ Ragbot is healthy and ready to use.\n\nAgent: %s\nRegion: %s",
-			healthStatus.Details.AgentName,
-			healthStatus.Details.Region,
-		)
-	} else {
+	if !healthStatus.Healthy {
 		var issueLines []string
 		for _, issue := range healthStatus.Issues {
-			issueLines = append(issueLines, fmt.Sprintf("• %s: %s", issue.Component, issue.Message))
+			issueLines = append(issueLines, fmt.Sprintf("• *%s:* %s", issue.Component, issue.Message))
 		}
-		responseText = "❌ Ragbot has issues:\n\n" + strings.Join(issueLines, "\n")
+
+		blocks := append([]slack.Block{titleBlock("❌", "Ragbot has issues")}, textBlocks(strings.Join(issueLines, "\n"))...)
+		h.respondToCommandWithBlocks(cmd, "Ragbot has issues", blocks)
+		return
+	}
+
+	blocks := []slack.Block{titleBlock("✅", "Ragbot is healthy and ready to use")}
+	blocks = append(blocks, fieldBlocks([]blockField{
+		{"Agent", healthStatus.Details.AgentName},
+		{"Region", healthStatus.Details.Region},
+	})...)
+
+	h.respondToCommandWithBlocks(cmd, "Ragbot is healthy and ready to use", blocks)
+}
+
+// HandleSearch handles the /ragbot-search command
+func (h *CommandHandler) HandleSearch(cmd slack.SlashCommand, args commandArgs) {
+	utils.LogInfo(fmt.Sprintf("Processing /ragbot-search command"))
+
+	query := args.Arg("query")
+	topK := services.DefaultRetrieveResults
+	if args.Has("top-k") {
+		topK, _ = strconv.Atoi(args.Flag("top-k"))
+	}
+
+	// Filters were validated when the command was parsed
+	var filters []types.MetadataFilter
+	for _, value := range args.Flags("filter") {
+		filter, _ := utils.ParseMetadataFilter(value)
+		filters = append(filters, filter)
+	}
+
+	// The channel's filters always apply on top of the ones requested
+	filters = append(filters, h.filters.Resolve(cmd.ChannelID, cmd.UserID)...)
+
+	response, err := h.bedrockService.RetrieveFromKnowledgeBase(query, topK, filters)
+	if err != nil {
+		utils.LogError(err, "Error in /ragbot-search")
+		h.respondToCommand(cmd, "Error searching the knowledge base: "+err.Error())
+		return
 	}
 
-	h.respondToCommand(cmd, responseText)
+	// Check if response contains an error
+	if errorResp, ok := response.(types.ErrorResponse); ok {
+		utils.LogError(fmt.Errorf(errorResp.Error), "Error in /ragbot-search")
+		h.respondToCommand(cmd, "Error searching the knowledge base: "+errorResp.Error)
+		return
+	}
+
+	results, ok := response.(types.RetrievalResults)
+	if !ok {
+		h.respondToCommand(cmd, fmt.Sprintf("%v", response))
+		return
+	}
+
+	h.respondToCommandWithBlocks(
+		cmd,
+		fmt.Sprintf("%d knowledge base results for: %s", len(results.Results), query),
+		retrievalBlocks(results),
+	)
 }
 
-// respondToCommand responds to a slash command with a message
+// HandleReset handles the /ragbot-reset command
+func (h *CommandHandler) HandleReset(cmd slack.SlashCommand, args commandArgs) {
+	utils.LogInfo(fmt.Sprintf("Processing /ragbot-reset command"))
+
+	var channel, thread string
+	if link := args.Arg("thread link"); link != "" {
+		var ok bool
+		channel, thread, ok = parseThreadLink(link)
+		if !ok {
+			h.respondToCommand(cmd, "That is not a thread link. Use \"Copy link\" on a message in the thread.")
+			return
+		}
+		if !utils.IsMaintainer(cmd.UserID) && !askedInThread(h.history, cmd.UserID, channel, thread) {
+			h.respondToCommand(cmd, "You can only reset threads you asked about recently. Ask a bot maintainer to reset other threads.")
+			return
+		}
+	} else {
+		// Default to the most recent thread the user asked about in this channel
+		for _, entry := range h.history.Recent(cmd.UserID, 0) {
+			if entry.Channel == cmd.ChannelID {
+				channel, thread = entry.Channel, entry.ThreadTS
+				break
+			}
+		}
+		if thread == "" {
+			h.respondToCommand(cmd, "You haven't asked anything in this channel recently. Pass a thread link to reset a specific thread.")
+			return
+		}
+	}
+
+	if _, err := resetSession(h.bedrockService, h.sessions, channel, thread); err != nil {
+		utils.LogError(err, "Error in /ragbot-reset")
+		h.respondToCommand(cmd, "Started a new session, but it could not be saved and will be lost on restart: "+err.Error())
+		return
+	}
+
+	h.respondToCommand(cmd, fmt.Sprintf("Started a new session for the thread %s in <#%s>.", thread, channel))
+}
+
+// HandleSchedule handles the /ragbot-schedule command
+func (h *CommandHandler) HandleSchedule(cmd slack.SlashCommand, args commandArgs) {
+	utils.LogInfo(fmt.Sprintf("Processing /ragbot-schedule command"))
+
+	schedules := h.scheduler.List()
+	h.respondToCommandWithBlocks(cmd, fmt.Sprintf("%d sync schedules", len(schedules)), scheduleBlocks(schedules))
+}
+
+// HandleSchedulePause handles the /ragbot-schedule pause command
+func (h *CommandHandler) HandleSchedulePause(cmd slack.SlashCommand, args commandArgs) {
+	utils.LogInfo(fmt.Sprintf("Processing /ragbot-schedule pause command from user %s", cmd.UserID))
+
+	name := args.Arg("name")
+	if err := h.scheduler.Pause(name); err != nil {
+		h.respondToCommand(cmd, err.Error())
+		return
+	}
+	h.respondToCommand(cmd, fmt.Sprintf("Schedule %s paused by <@%s>.", name, cmd.UserID))
+}
+
+// HandleScheduleResume handles the /ragbot-schedule resume command
+func (h *CommandHandler) HandleScheduleResume(cmd slack.SlashCommand, args commandArgs) {
+	utils.LogInfo(fmt.Sprintf("Processing /ragbot-schedule resume command from user %s", cmd.UserID))
+
+	name := args.Arg("name")
+	if err := h.scheduler.Resume(name); err != nil {
+		h.respondToCommand(cmd, err.Error())
+		return
+	}
+	h.respondToCommand(cmd, fmt.Sprintf("Schedule %s resumed by <@%s>.", name, cmd.UserID))
+}
+
+// respondToCommand responds to a slash command with a message, rendered as Block Kit sections
 func (h *CommandHandler) respondToCommand(cmd slack.SlashCommand, text string) {
+	h.respondToCommandWithBlocks(cmd, text, textBlocks(text))
+}
+
+// respondToCommandWithBlocks responds to a slash command with a Block Kit message.
+// The text is shown in notifications and used on its own when there are no blocks.
+func (h *CommandHandler) respondToCommandWithBlocks(cmd slack.SlashCommand, text string, blocks []slack.Block) {
 	// Check if we have a response URL to use
 	if cmd.ResponseURL != "" {
 		utils.LogInfo(fmt.Sprintf("Responding to command using response_url: %s", cmd.ResponseURL))
 
 		// Create the message payload as a map instead of using slack.Message
 		response := map[string]interface{}{
-			"response_type": "in_channel", // Make the response visible to everyone in the channel
-			"text":         text,
+			"response_type": h.responseType(cmd),
+			"text":          text,
+		}
+		if len(blocks) > 0 {
+			response["blocks"] = blocks
 		}
 
 		// Convert the response to JSON
@@ -406,18 +711,68 @@ func (h *CommandHandler) respondToCommand(cmd slack.SlashCommand, text string) {
 			utils.LogError(fmt.Errorf("received non-200 status code: %d", resp.StatusCode), "Error from Slack API")
 		}
 	} else {
-		// Fall back to posting a message directly
+		// Fall back to posting a message directly, only to the user unless the response is
+		// meant for the channel or the command was run in a direct message
 		utils.LogInfo("No response URL available, posting message directly")
-		_, _, err := h.api.PostMessage(
-			cmd.ChannelID,
+		options := []slack.MsgOption{
 			slack.MsgOptionText(text, false),
 			slack.MsgOptionPostMessageParameters(slack.PostMessageParameters{
 				Username: "RagBot",
 			}),
-		)
+		}
+		if len(blocks) > 0 {
+			options = append(options, slack.MsgOptionBlocks(blocks...))
+		}
 
+		var err error
+		if h.responseType(cmd) == slack.ResponseTypeInChannel || cmd.ChannelID == cmd.UserID {
+			_, _, err = h.api.PostMessage(cmd.ChannelID, options...)
+		} else {
+			_, err = h.api.PostEphemeral(cmd.ChannelID, cmd.UserID, options...)
+		}
 		if err != nil {
 			utils.LogError(err, "Error posting command response")
 		}
 	}
 }
+
+// responseKey identifies a running command by its response URL, or by its user and channel
+// when it has none
+func responseKey(cmd slack.SlashCommand) string {
+	if cmd.ResponseURL != "" {
+		return cmd.ResponseURL
+	}
+	return cmd.UserID + ":" + cmd.ChannelID
+}
+
+// setResponseType records whether the responses to a running command are posted to the channel
+func (h *CommandHandler) setResponseType(cmd slack.SlashCommand, inChannel bool) {
+	h.responseTypesMu.Lock()
+	defer h.responseTypesMu.Unlock()
+
+	if inChannel {
+		h.responseTypes[responseKey(cmd)] = slack.ResponseTypeInChannel
+	} else {
+		h.responseTypes[responseKey(cmd)] = slack.ResponseTypeEphemeral
+	}
+}
+
+// clearResponseType forgets the response type of a command once it has finished
+func (h *CommandHandler) clearResponseType(cmd slack.SlashCommand) {
+	h.responseTypesMu.Lock()
+	defer h.responseTypesMu.Unlock()
+
+	delete(h.responseTypes, responseKey(cmd))
+}
+
+// responseType returns the response type of a command. Responses outside a command, such as
+// button clicks, are only shown to the user who clicked.
+func (h *CommandHandler) responseType(cmd slack.SlashCommand) string {
+	h.responseTypesMu.Lock()
+	defer h.responseTypesMu.Unlock()
+
+	if responseType, ok := h.responseTypes[responseKey(cmd)]; ok {
+		return responseType
+	}
+	return slack.ResponseTypeEphemeral
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+
+	"slack-rag-server/src/services"
+	"slack-rag-server/src/types"
+	"slack-rag-server/src/utils"
+)
+
+// Action IDs for the buttons on action confirmation messages
+const (
+	ActionConfirmApprove = "agent_action_approve"
+	ActionConfirmDeny    = "agent_action_deny"
+)
+
+// defaultConfirmationTimeout is how long a user has to approve an action when
+// RAGBOT_CONFIRMATION_TIMEOUT_SECONDS is not set
+const defaultConfirmationTimeout = 5 * time.Minute
+
+// confirmationParameterLength is the maximum length of a parameter value shown in an action
+// confirmation, so long values cannot push the message past Slack's limits
+const confirmationParameterLength = 300
+
+// pendingConfirmation is an agent action waiting for the requesting user's decision
+type pendingConfirmation struct {
+	user     string
+	decision chan string
+}
+
+// confirmationStore tracks the agent actions waiting for approval
+type confirmationStore struct {
+	mu      sync.Mutex
+	pending map[string]*pendingConfirmation
+	timeout time.Duration
+}
+
+// newConfirmationStore creates a new confirmationStore
+func newConfirmationStore() *confirmationStore {
+	timeout := defaultConfirmationTimeout
+	if value := os.Getenv("RAGBOT_CONFIRMATION_TIMEOUT_SECONDS"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			timeout = time.Duration(parsed) * time.Second
+		}
+	}
+
+	return &confirmationStore{
+		pending: make(map[string]*pendingConfirmation),
+		timeout: timeout,
+	}
+}
+
+// actionConfirmer returns a confirmer that asks the user in the message's thread to approve
+// agent actions, waiting for them to click Approve or Deny
+func (h *MessageHandler) actionConfirmer(channel, timestamp, user string, private bool) types.ActionConfirmer {
+	return func(invocation types.ActionInvocation) (bool, string) {
+		id := fmt.Sprintf("%s-%d", timestamp, time.Now().UnixNano())
+		pending := &pendingConfirmation{user: user, decision: make(chan string, 1)}
+
+		h.confirmations.mu.Lock()
+		h.confirmations.pending[id] = pending
+		h.confirmations.mu.Unlock()
+
+		defer func() {
+			h.confirmations.mu.Lock()
+			delete(h.confirmations.pending, id)
+			h.confirmations.mu.Unlock()
+		}()
+
+		blocks := confirmationBlocks(id, user, invocation, h.confirmations.timeout)
+		fallback := fmt.Sprintf("The agent wants to run %s and needs your approval", services.ActionName(invocation))
+
+		var messageTS string
+		var err error
+		if private {
+			err = utils.SendEphemeralBlocks(h.api, channel, user, fallback, timestamp, blocks)
+		} else {
+			_, messageTS, err = h.api.PostMessage(channel,
+				slack.MsgOptionText(fallback, false),
+				slack.MsgOptionTS(timestamp),
+				slack.MsgOptionBlocks(blocks...),
+			)
+		}
+		if err != nil {
+			utils.LogError(err, "Error sending action confirmation")
+			return false, "could not ask the user for approval"
+		}
+
+		select {
+		case decision := <-pending.decision:
+			return decision == ActionConfirmApprove, fmt.Sprintf("%s by %s", confirmationVerb(decision), user)
+		case <-time.After(h.confirmations.timeout):
+			expired := confirmationResultBlocks(invocation, fmt.Sprintf(":hourglass: Approval request expired after %s, the action was not run.", h.confirmations.timeout))
+			if messageTS != "" {
+				_, _, _, err = h.api.UpdateMessage(channel, messageTS,
+					slack.MsgOptionText("Approval request expired", false),
+					slack.MsgOptionBlocks(expired...),
+				)
+			} else {
+				err = utils.SendEphemeralBlocks(h.api, channel, user, "Approval request expired", timestamp, expired)
+			}
+			if err != nil {
+				utils.LogError(err, "Error updating expired action confirmation")
+			}
+			return false, "approval request expired"
+		}
+	}
+}
+
+// HandleConfirmationAction delivers an Approve or Deny click to the action waiting for it.
+// Only the user who asked the question can decide.
+func (h *MessageHandler) HandleConfirmationAction(callback slack.InteractionCallback, action *slack.BlockAction) {
+	h.confirmations.mu.Lock()
+	pending, ok := h.confirmations.pending[action.Value]
+	h.confirmations.mu.Unlock()
+
+	if !ok {
+		h.replaceInteractionMessage(callback, "This approval request has expired or was already answered.")
+		return
+	}
+
+	if callback.User.ID != pending.user {
+		if err := utils.SendEphemeralMessage(h.api, callback.Channel.ID, callback.User.ID,
+			fmt.Sprintf("Only <@%s> can approve or deny this action.", pending.user), ""); err != nil {
+			utils.LogError(err, "Error sending confirmation permission message")
+		}
+		return
+	}
+
+	select {
+	case pending.decision <- action.ActionID:
+	default:
+		// A decision was already made
+		return
+	}
+
+	icon := ":white_check_mark:"
+	if action.ActionID == ActionConfirmDeny {
+		icon = ":no_entry_sign:"
+	}
+	h.replaceInteractionMessage(callback, fmt.Sprintf("%s Action %s by <@%s>.", icon, confirmationVerb(action.ActionID), callback.User.ID))
+}
+
+// replaceInteractionMessage replaces the message a button was clicked on, keeping its
+// description of the action and swapping the buttons for the given status
+func (h *MessageHandler) replaceInteractionMessage(callback slack.InteractionCallback, status string) {
+	blocks := []slack.Block{}
+	for _, block := range callback.Message.Blocks.BlockSet {
+		if block.BlockType() != slack.MBTAction {
+			blocks = append(blocks, block)
+		}
+	}
+	blocks = append(blocks, slack.NewContextBlock("",
+		slack.NewTextBlockObject(slack.MarkdownType, status, false, false),
+	))
+
+	_, _, err := h.api.PostMessage(callback.Channel.ID,
+		slack.MsgOptionReplaceOriginal(callback.ResponseURL),
+		slack.MsgOptionText(status, false),
+		slack.MsgOptionBlocks(blocks...),
+	)
+	if err != nil {
+		utils.LogError(err, "Error updating action confirmation")
+	}
+}
+
+// confirmationVerb describes a confirmation button decision
+func confirmationVerb(actionID string) string {
+	if actionID == ActionConfirmApprove {
+		return "approved"
+	}
+	return "denied"
+}
+
+// confirmationBlocks renders a pending agent action with Approve and Deny buttons
+func confirmationBlocks(id, user string, invocation types.ActionInvocation, timeout time.Duration) []slack.Block {
+	blocks := []slack.Block{
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("<@%s>, the agent wants to run an action and needs your approval.", user), false, false),
+			nil,
+			nil,
+		),
+	}
+	blocks = append(blocks, actionDescriptionBlocks(invocation)...)
+
+	approve := slack.NewButtonBlockElement(ActionConfirmApprove, id, slack.NewTextBlockObject(slack.PlainTextType, "Approve", false, false))
+	approve.Style = slack.StylePrimary
+	deny := slack.NewButtonBlockElement(ActionConfirmDeny, id, slack.NewTextBlockObject(slack.PlainTextType, "Deny", false, false))
+	deny.Style = slack.StyleDanger
+
+	return append(blocks,
+		slack.NewActionBlock("agent_action_confirmation", approve, deny),
+		slack.NewContextBlock("",
+			slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("This request expires in %s.", timeout), false, false),
+		),
+	)
+}
+
+// confirmationResultBlocks renders an agent action along with the outcome of its approval request
+func confirmationResultBlocks(invocation types.ActionInvocation, status string) []slack.Block {
+	blocks := actionDescriptionBlocks(invocation)
+	return append(blocks, slack.NewContextBlock("",
+		slack.NewTextBlockObject(slack.MarkdownType, status, false, false),
+	))
+}
+
+// actionDescriptionBlocks describes an agent action and its parameters
+func actionDescriptionBlocks(invocation types.ActionInvocation) []slack.Block {
+	fields := []*slack.TextBlockObject{
+		slack.NewTextBlockObject(slack.MarkdownType, "*Action group:*\n"+invocation.ActionGroup, false, false),
+		slack.NewTextBlockObject(slack.MarkdownType, "*Action:*\n`"+services.ActionName(invocation)+"`", false, false),
+	}
+
+	var names []string
+	for name := range invocation.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var parameters []string
+	for _, name := range names {
+		value := utils.TruncateText(invocation.Parameters[name], confirmationParameterLength)
+		parameters = append(parameters, fmt.Sprintf("• *%s:* %s", name, value))
+	}
+	if len(parameters) == 0 {
+		parameters = append(parameters, "_No parameters_")
+	}
+
+	return []slack.Block{
+		slack.NewSectionBlock(nil, fields, nil),
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, utils.TruncateText("*Parameters:*\n"+strings.Join(parameters, "\n"), maxSectionLength-1), false, false),
+			nil,
+			nil,
+		),
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+
+	"slack-rag-server/src/utils"
+)
+
+// approxCharsPerToken is a rough character-to-token ratio used to size thread context
+const approxCharsPerToken = 4
+
+// defaultThreadContextTokens is the token budget for thread context when
+// RAGBOT_THREAD_CONTEXT_TOKENS is not set
+const defaultThreadContextTokens = 2000
+
+// userMentionPattern matches Slack user mentions such as <@U123> or <@U123|name>
+var userMentionPattern = regexp.MustCompile(`<@([A-Z0-9]+)(?:\|[^>]*)?>`)
+
+// threadContextBudget returns the maximum number of characters of thread context to send
+func threadContextBudget() int {
+	tokens := defaultThreadContextTokens
+	if value := os.Getenv("RAGBOT_THREAD_CONTEXT_TOKENS"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			tokens = parsed
+		}
+	}
+	return tokens * approxCharsPerToken
+}
+
+// priorThreadContext returns the messages posted in a thread before the current message,
+// formatted for the agent. It returns an empty string when the bot has already replied
+// in the thread, since the agent session already holds that conversation.
+func (h *MessageHandler) priorThreadContext(channel, threadTS, currentTS string) string {
+	messages, err := utils.GetThreadMessages(h.api, channel, threadTS)
+	if err != nil {
+		utils.LogError(err, "Error fetching thread history")
+		return ""
+	}
+
+	botUserID := h.getBotUserID()
+	current := parseSlackTimestamp(currentTS)
+
+	var prior []slack.Message
+	for _, message := range messages {
+		if botUserID != "" && message.User == botUserID {
+			return ""
+		}
+		if parseSlackTimestamp(message.Timestamp) < current {
+			prior = append(prior, message)
+		}
+	}
+
+	return h.formatThreadContext(prior)
+}
+
+// formatThreadContext renders Slack messages as "name: text" lines with mentions resolved
+// to names. When the thread exceeds the context budget the parent message is kept and
+// the oldest replies are dropped.
+func (h *MessageHandler) formatThreadContext(messages []slack.Message) string {
+	var lines []string
+	for _, message := range messages {
+		text := strings.TrimSpace(message.Text)
+		if text == "" {
+			continue
+		}
+
+		author := message.Username
+		if message.User != "" {
+			author = h.directory.GetUserName(message.User)
+		}
+		if author == "" {
+			author = "bot"
+		}
+
+		text = userMentionPattern.ReplaceAllStringFunc(text, func(mention string) string {
+			return "@" + h.directory.GetUserName(userMentionPattern.FindStringSubmatch(mention)[1])
+		})
+		lines = append(lines, fmt.Sprintf("%s: %s", author, text))
+	}
+
+	return trimToBudget(lines, threadContextBudget())
+}
+
+// trimToBudget keeps the first line and as many of the most recent lines as fit in budget characters
+func trimToBudget(lines []string, budget int) string {
+	if len(lines) == 0 {
+		return ""
+	}
+
+	first := lines[0]
+	if len(first) > budget {
+		return utils.TruncateText(first, budget)
+	}
+
+	remaining := budget - len(first)
+	start := len(lines)
+	for start > 1 && len(lines[start-1])+1 <= remaining {
+		remaining -= len(lines[start-1]) + 1
+		start--
+	}
+
+	result := []string{first}
+	if omitted := start - 1; omitted > 0 {
+		result = append(result, fmt.Sprintf("[... %d earlier messages omitted ...]", omitted))
+	}
+	result = append(result, lines[start:]...)
+	return strings.Join(result, "\n")
+}
+
+// getBotUserID looks up the bot's own user ID once and caches it
+func (h *MessageHandler) getBotUserID() string {
+	h.botUserOnce.Do(func() {
+		response, err := h.api.AuthTest()
+		if err != nil {
+			utils.LogError(err, "Error looking up bot user ID")
+			return
+		}
+		h.botUserID = response.UserID
+	})
+	return h.botUserID
+}
+
+// parseSlackTimestamp converts a Slack message timestamp to a comparable number
+func parseSlackTimestamp(ts string) float64 {
+	value, err := strconv.ParseFloat(ts, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+
+	"slack-rag-server/src/types"
+	"slack-rag-server/src/utils"
+)
+
+// Action IDs of the feedback buttons below answers
+const (
+	ActionFeedbackUp   = "answer_feedback_up"
+	ActionFeedbackDown = "answer_feedback_down"
+)
+
+// feedbackBlock renders the thumbs up and thumbs down buttons below an answer. The button
+// value carries the alias that answered, so feedback is attributed even after a restart.
+func feedbackBlock(outcome types.AnswerOutcome) slack.Block {
+	value := outcome.AliasID + "|" + outcome.AnswerID
+	up := slack.NewButtonBlockElement(ActionFeedbackUp, value, slack.NewTextBlockObject(slack.PlainTextType, "👍 Helpful", true, false))
+	down := slack.NewButtonBlockElement(ActionFeedbackDown, value, slack.NewTextBlockObject(slack.PlainTextType, "👎 Not helpful", true, false))
+	return slack.NewActionBlock("answer_feedback", up, down)
+}
+
+// HandleFeedbackAction records a thumbs up or thumbs down on an answer and thanks the user
+func (h *MessageHandler) HandleFeedbackAction(callback slack.InteractionCallback, action *slack.BlockAction) {
+	aliasID, answerID, ok := strings.Cut(action.Value, "|")
+	if !ok {
+		utils.LogWarning("Invalid feedback value: " + action.Value)
+		return
+	}
+
+	score, verdict := 1, "helpful"
+	if action.ActionID == ActionFeedbackDown {
+		score, verdict = -1, "not helpful"
+	}
+
+	h.experiment.RecordFeedback(types.AnswerFeedback{
+		AnswerID: answerID,
+		AliasID:  aliasID,
+		User:     callback.User.ID,
+		Score:    score,
+	})
+
+	h.replaceInteractionMessage(callback, fmt.Sprintf("<@%s> rated this answer %s. Thanks for the feedback!", callback.User.ID, verdict))
+}
+
+// HandleAgentExperiment handles the /ragbot-agent experiment command, comparing the latency,
+// error rate and feedback of the aliases that answered questions
+func (h *CommandHandler) HandleAgentExperiment(cmd slack.SlashCommand, args commandArgs) {
+	utils.LogInfo(fmt.Sprintf("Processing /ragbot-agent experiment command"))
+
+	report := h.experiment.Report()
+
+	split := "Not running, every thread is answered by the default alias"
+	if report.CandidateAliasID != "" && report.CandidatePercent > 0 {
+		split = fmt.Sprintf("%d%% of threads go to `%s`", report.CandidatePercent, report.CandidateAliasID)
+	}
+
+	blocks := []slack.Block{
+		titleBlock("🧪", "Agent alias experiment"),
+		contextBlock(fmt.Sprintf("Traffic split: %s · Since %s", split, utils.SlackDate(report.Since))),
+	}
+
+	if len(report.Aliases) == 0 {
+		blocks = append(blocks, textBlocks("_No answers have been recorded yet._")...)
+		h.respondToCommandWithBlocks(cmd, "No answers have been recorded yet", blocks)
+		return
+	}
+
+	for _, alias := range report.Aliases {
+		aliasID := alias.AliasID
+		if aliasID == "" {
+			aliasID = "default"
+		}
+
+		feedback := "No votes"
+		if votes := alias.ThumbsUp + alias.ThumbsDown; votes > 0 {
+			feedback = fmt.Sprintf("%.0f%% helpful (👍 %d · 👎 %d)", alias.FeedbackScore*100, alias.ThumbsUp, alias.ThumbsDown)
+		}
+
+		blocks = append(blocks,
+			slack.NewDividerBlock(),
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*`%s`* (%s)", aliasID, alias.Role), false, false), nil, nil),
+		)
+		blocks = append(blocks, fieldBlocks([]blockField{
+			{Label: "Answers", Value: fmt.Sprintf("%d", alias.Answers)},
+			{Label: "Error rate", Value: fmt.Sprintf("%.1f%% (%d errors)", alias.ErrorRate*100, alias.Errors)},
+			{Label: "Guardrail blocks", Value: fmt.Sprintf("%d", alias.Blocked)},
+			{Label: "Latency", Value: fmt.Sprintf("avg %s · p50 %s · p95 %s", formatLatency(alias.AvgLatencyMs), formatLatency(alias.P50LatencyMs), formatLatency(alias.P95LatencyMs))},
+			{Label: "Feedback", Value: feedback},
+		})...)
+	}
+
+	h.respondToCommandWithBlocks(cmd, fmt.Sprintf("Agent alias experiment: %d aliases", len(report.Aliases)), blocks)
+}
+
+// formatLatency formats a latency in milliseconds as seconds
+func formatLatency(ms int64) string {
+	return fmt.Sprintf("%.1fs", float64(ms)/1000)
+}
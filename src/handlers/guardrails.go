@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"fmt"
+
+	"slack-rag-server/src/services"
+	"slack-rag-server/src/types"
+)
+
+// GuardrailMessage explains to the asker that a guardrail blocked their question or withheld
+// the answer, and which policies were triggered
+func GuardrailMessage(intervention *types.GuardrailIntervention) string {
+	subject := "Your question was blocked"
+	if intervention.Stage == services.GuardrailStageOutput {
+		subject = "The answer to your question was withheld"
+	}
+
+	text := ":no_entry_sign: " + subject + " by our content guardrails"
+	if len(intervention.Policies) > 0 {
+		text += " because it matched: " + services.FormatGuardrailPolicies(intervention.Policies)
+	}
+	return text + ".\nPlease rephrase your question. If you think this is a mistake, let the bot maintainers know."
+}
+
+// recordGuardrailIntervention records a blocked question or answer in the audit trail
+func (h *MessageHandler) recordGuardrailIntervention(channel, user, sessionID string, intervention *types.GuardrailIntervention) {
+	h.audit.Record(types.AuditEntry{
+		Event:      "guardrail_intervened",
+		SessionID:  sessionID,
+		User:       user,
+		Channel:    channel,
+		Action:     intervention.Source,
+		Parameters: map[string]string{"stage": intervention.Stage},
+		Result:     fmt.Sprintf("blocked: %s", services.FormatGuardrailPolicies(intervention.Policies)),
+	})
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+
+	"slack-rag-server/src/services"
+	"slack-rag-server/src/types"
+	"slack-rag-server/src/utils"
+)
+
+// Action IDs for the buttons rendered on the App Home tab
+const (
+	ActionHomeRefresh         = "home_refresh"
+	ActionHomeSyncDataSource  = "home_sync_datasource"
+	ActionHomeListDataSources = "home_list_datasources"
+	ActionHomeResetSession    = "home_reset_session"
+)
+
+// homeHistoryLimit is the number of recent questions shown on the App Home tab
+const homeHistoryLimit = 5
+
+// homeAnswerPreviewLength and homeQuestionPreviewLength are the maximum lengths of the answer
+// and question previews on the App Home tab. Together with the labels they stay well under
+// the 3000 characters Slack allows in a section, which would otherwise fail the whole view.
+const (
+	homeAnswerPreviewLength   = 280
+	homeQuestionPreviewLength = 1000
+)
+
+// HomeHandler renders the App Home tab
+type HomeHandler struct {
+	api            *slack.Client
+	bedrockService *services.BedrockService
+	history        *services.HistoryStore
+}
+
+// NewHomeHandler creates a new HomeHandler
+func NewHomeHandler(api *slack.Client, bedrockService *services.BedrockService, history *services.HistoryStore) *HomeHandler {
+	return &HomeHandler{
+		api:            api,
+		bedrockService: bedrockService,
+		history:        history,
+	}
+}
+
+// HandleAppHomeOpened publishes a fresh App Home view whenever a user opens the Home tab
+func (h *HomeHandler) HandleAppHomeOpened(userID, tab string) {
+	if tab != "" && tab != "home" {
+		return
+	}
+
+	utils.LogInfo(fmt.Sprintf("Publishing App Home for user %s", userID))
+	h.PublishHome(userID)
+}
+
+// PublishHome builds and publishes the App Home view for a user
+func (h *HomeHandler) PublishHome(userID string) {
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, "Ragbot", true, false)),
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, "Ask questions by mentioning @Ragbot in a channel or sending me a direct message.", false, false),
+			nil,
+			nil,
+		),
+		slack.NewDividerBlock(),
+	}
+
+	blocks = append(blocks, h.statusBlocks()...)
+	blocks = append(blocks, slack.NewDividerBlock())
+	blocks = append(blocks, h.historyBlocks(userID)...)
+	blocks = append(blocks, slack.NewDividerBlock())
+	blocks = append(blocks, h.actionBlocks(userID)...)
+
+	view := slack.HomeTabViewRequest{
+		Type:   slack.VTHomeTab,
+		Blocks: slack.Blocks{BlockSet: blocks},
+	}
+
+	if _, err := h.api.PublishView(userID, view, ""); err != nil {
+		utils.LogError(err, "Error publishing App Home view")
+	}
+}
+
+// statusBlocks renders agent/knowledge base health and the last ingestion job
+func (h *HomeHandler) statusBlocks() []slack.Block {
+	var healthText string
+	healthStatus, err := h.bedrockService.CheckBedrockAgentHealth()
+	if err != nil {
+		healthText = "❌ Unable to check health: " + err.Error()
+	} else if healthStatus.Healthy {
+		healthText = fmt.Sprintf("✅ Healthy\nAgent: %s", healthStatus.Details.AgentName)
+		if healthStatus.Details.KnowledgeBaseName != "" {
+			healthText += fmt.Sprintf("\nKnowledge Base: %s", healthStatus.Details.KnowledgeBaseName)
+		}
+	} else {
+		var issueLines []string
+		for _, issue := range healthStatus.Issues {
+			issueLines = append(issueLines, fmt.Sprintf("• %s: %s", issue.Component, issue.Message))
+		}
+		healthText = "❌ Issues detected\n" + strings.Join(issueLines, "\n")
+	}
+
+	var jobText string
+	response, err := h.bedrockService.GetDataSource("")
+	if err != nil {
+		jobText = "Unable to get ingestion job status: " + err.Error()
+	} else if errorResp, ok := response.(types.ErrorResponse); ok {
+		jobText = "Unable to get ingestion job status: " + errorResp.Error
+	} else if dsInfo, ok := response.(types.DataSourceInfo); ok {
+		jobText = fmt.Sprintf("Status: %s\nLast sync: %s", dsInfo.Status, utils.FormatDate(dsInfo.UpdatedAt))
+	} else {
+		jobText = "No ingestion jobs found"
+	}
+
+	return []slack.Block{
+		slack.NewSectionBlock(
+			nil,
+			[]*slack.TextBlockObject{
+				slack.NewTextBlockObject(slack.MarkdownType, "*Service health*\n"+healthText, false, false),
+				slack.NewTextBlockObject(slack.MarkdownType, "*Last ingestion job*\n"+jobText, false, false),
+			},
+			nil,
+		),
+	}
+}
+
+// historyBlocks renders the user's most recent questions and answers
+func (h *HomeHandler) historyBlocks(userID string) []slack.Block {
+	blocks := []slack.Block{
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, "*Your recent questions*", false, false),
+			nil,
+			nil,
+		),
+	}
+
+	entries := h.history.Recent(userID, homeHistoryLimit)
+	if len(entries) == 0 {
+		return append(blocks, slack.NewContextBlock("",
+			slack.NewTextBlockObject(slack.MarkdownType, "You haven't asked anything yet.", false, false),
+		))
+	}
+
+	for _, entry := range entries {
+		status := "✅"
+		if !entry.Success {
+			status = "❌"
+		}
+
+		question := utils.TruncateText(entry.Question, homeQuestionPreviewLength)
+		answer := utils.TruncateText(entry.Answer, homeAnswerPreviewLength)
+
+		// Let the user start over in the thread the question was asked in
+		reset := slack.NewButtonBlockElement(ActionHomeResetSession, entry.Channel+":"+entry.ThreadTS,
+			slack.NewTextBlockObject(slack.PlainTextType, "Reset session", true, false))
+
+		blocks = append(blocks,
+			slack.NewSectionBlock(
+				slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s *Q:* %s\n*A:* %s", status, question, answer), false, false),
+				nil,
+				slack.NewAccessory(reset),
+			),
+			slack.NewContextBlock("",
+				slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("Asked %s in <#%s>", utils.FormatDate(entry.AskedAt), entry.Channel), false, false),
+			),
+		)
+	}
+
+	return blocks
+}
+
+// actionBlocks renders quick actions, including maintainer-only actions
+func (h *HomeHandler) actionBlocks(userID string) []slack.Block {
+	elements := []slack.BlockElement{
+		slack.NewButtonBlockElement(ActionHomeRefresh, "refresh",
+			slack.NewTextBlockObject(slack.PlainTextType, "Refresh", true, false)),
+	}
+
+	if utils.IsMaintainer(userID) {
+		elements = append(elements,
+			slack.NewButtonBlockElement(ActionHomeSyncDataSource, "sync",
+				slack.NewTextBlockObject(slack.PlainTextType, "Sync data source", true, false)).WithStyle(slack.StylePrimary),
+			slack.NewButtonBlockElement(ActionHomeListDataSources, "list",
+				slack.NewTextBlockObject(slack.PlainTextType, "View data sources", true, false)),
+		)
+	}
+
+	return []slack.Block{
+		slack.NewActionBlock("home_actions", elements...),
+	}
+}
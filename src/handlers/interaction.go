@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+
+	"slack-rag-server/src/utils"
+)
+
+// InteractionHandler handles Slack interactivity payloads (buttons, shortcuts and modals)
+type InteractionHandler struct {
+	api            *slack.Client
+	messageHandler *MessageHandler
+	homeHandler    *HomeHandler
+	commandHandler *CommandHandler
+}
+
+// NewInteractionHandler creates a new InteractionHandler
+func NewInteractionHandler(api *slack.Client, messageHandler *MessageHandler, homeHandler *HomeHandler, commandHandler *CommandHandler) *InteractionHandler {
+	return &InteractionHandler{
+		api:            api,
+		messageHandler: messageHandler,
+		homeHandler:    homeHandler,
+		commandHandler: commandHandler,
+	}
+}
+
+// HandleInteraction dispatches an interactivity payload based on its type
+func (h *InteractionHandler) HandleInteraction(callback slack.InteractionCallback) {
+	switch callback.Type {
+	case slack.InteractionTypeBlockActions:
+		for _, action := range callback.ActionCallback.BlockActions {
+			h.handleBlockAction(callback, action)
+		}
+	case slack.InteractionTypeMessageAction, slack.InteractionTypeShortcut:
+		if callback.CallbackID == CallbackAskMessageShortcut || callback.CallbackID == CallbackAskGlobalShortcut {
+			h.handleShortcut(callback)
+		} else {
+			utils.LogInfo(fmt.Sprintf("Unhandled shortcut: %s", callback.CallbackID))
+		}
+	case slack.InteractionTypeViewSubmission:
+		if callback.View.CallbackID == CallbackAskModal {
+			h.handleAskModalSubmission(callback)
+		} else {
+			utils.LogInfo(fmt.Sprintf("Unhandled view submission: %s", callback.View.CallbackID))
+		}
+	default:
+		utils.LogInfo(fmt.Sprintf("Unhandled interaction type: %s", callback.Type))
+	}
+}
+
+// handleBlockAction handles a single button click
+func (h *InteractionHandler) handleBlockAction(callback slack.InteractionCallback, action *slack.BlockAction) {
+	userID := callback.User.ID
+	utils.LogInfo(fmt.Sprintf("Processing block action %s from user %s", action.ActionID, userID))
+
+	switch action.ActionID {
+	case ActionHomeRefresh:
+		h.homeHandler.PublishHome(userID)
+	case ActionHomeSyncDataSource:
+		if !h.requireMaintainer(userID) {
+			return
+		}
+		h.commandHandler.Dispatch(directCommand(userID, "/ragbot-sync-datasource"))
+		h.homeHandler.PublishHome(userID)
+	case ActionHomeListDataSources:
+		if !h.requireMaintainer(userID) {
+			return
+		}
+		h.commandHandler.Dispatch(directCommand(userID, "/ragbot-list-datasources"))
+	case ActionJobHistoryNext:
+		h.commandHandler.HandleJobHistoryPage(callback, action.Value)
+	case ActionHomeResetSession:
+		h.handleResetSession(userID, action.Value)
+	case ActionAgentPrepare, ActionAgentPromote:
+		if !h.requireMaintainer(userID) {
+			return
+		}
+		h.commandHandler.HandleAgentAction(callback, action)
+	case ActionConfirmApprove, ActionConfirmDeny:
+		h.messageHandler.HandleConfirmationAction(callback, action)
+	case ActionFeedbackUp, ActionFeedbackDown:
+		h.messageHandler.HandleFeedbackAction(callback, action)
+	default:
+		utils.LogInfo(fmt.Sprintf("Unhandled block action: %s", action.ActionID))
+	}
+}
+
+// handleResetSession starts a new agent session for the thread of a question on the App Home tab
+func (h *InteractionHandler) handleResetSession(userID, value string) {
+	channel, thread, ok := strings.Cut(value, ":")
+	if !ok {
+		utils.LogWarning("Invalid reset session value: " + value)
+		return
+	}
+
+	text := "Started a new session for this thread."
+	if _, err := h.messageHandler.ResetThreadSession(channel, thread); err != nil {
+		utils.LogError(err, "Error saving new session")
+		text = "Started a new session for this thread, but it could not be saved and will be lost on restart."
+	}
+
+	if err := utils.SendEphemeralMessage(h.api, channel, userID, text, thread); err != nil {
+		utils.LogError(err, "Error sending reset confirmation")
+	}
+}
+
+// requireMaintainer checks that a user is a maintainer and tells them if they are not
+func (h *InteractionHandler) requireMaintainer(userID string) bool {
+	if utils.IsMaintainer(userID) {
+		return true
+	}
+
+	if err := utils.SendSlackMessage(h.api, userID, "Sorry, only Ragbot maintainers can perform this action.", ""); err != nil {
+		utils.LogError(err, "Error sending permission message")
+	}
+	return false
+}
+
+// directCommand builds a slash command whose response is posted to the user's DM with the bot
+func directCommand(userID, command string) slack.SlashCommand {
+	return slack.SlashCommand{
+		Command:   command,
+		UserID:    userID,
+		ChannelID: userID,
+	}
+}
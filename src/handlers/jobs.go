@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+
+	"slack-rag-server/src/types"
+	"slack-rag-server/src/utils"
+)
+
+// ActionJobHistoryNext is the action ID of the button that loads older ingestion jobs
+const ActionJobHistoryNext = "job_history_next"
+
+// syncAllMonitorMinutes is how long /ragbot-sync-datasource --all waits for its jobs, short
+// enough for the summary to be posted before the command's response URL expires
+const syncAllMonitorMinutes = 25
+
+// pageTokenTTL is how long the pagination token behind an "Older jobs" button is kept
+const pageTokenTTL = 24 * time.Hour
+
+// savedPageToken is a pagination token of the ingestion job list
+type savedPageToken struct {
+	token   string
+	savedAt time.Time
+}
+
+// pageTokenStore keeps the pagination tokens of "Older jobs" buttons under short random keys.
+// Bedrock tokens can be longer than the 2000 characters Slack allows in a button value, and
+// random keys keep buttons posted before a restart from loading another listing's token.
+type pageTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]savedPageToken
+}
+
+// newPageTokenStore creates a new pageTokenStore
+func newPageTokenStore() *pageTokenStore {
+	return &pageTokenStore{tokens: make(map[string]savedPageToken)}
+}
+
+// save stores a pagination token and returns its key, dropping expired tokens
+func (s *pageTokenStore) save(token string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, saved := range s.tokens {
+		if time.Since(saved.savedAt) > pageTokenTTL {
+			delete(s.tokens, key)
+		}
+	}
+
+	key := newPageTokenKey()
+	s.tokens[key] = savedPageToken{token: token, savedAt: time.Now()}
+	return key
+}
+
+// newPageTokenKey returns a random key for a pagination token
+func newPageTokenKey() string {
+	key := make([]byte, 8)
+	if _, err := rand.Read(key); err != nil {
+		// Fall back to the time, which is still unlikely to repeat across restarts
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(key)
+}
+
+// load returns the pagination token saved under a key, if it has not expired
+func (s *pageTokenStore) load(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved, ok := s.tokens[key]
+	if !ok || time.Since(saved.savedAt) > pageTokenTTL {
+		return "", false
+	}
+	return saved.token, true
+}
+
+// jobHistoryBlocks renders a page of ingestion jobs of a data source, with a button for the
+// next page when there is one. pageKey is the key the next page's token is saved under.
+func jobHistoryBlocks(history types.IngestionJobHistory, limit int, dataSourceID, pageKey string) []slack.Block {
+	blocks := []slack.Block{
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*Recent ingestion jobs of data source `%s`*", dataSourceID), false, false),
+			nil,
+			nil,
+		),
+	}
+
+	if len(history.Jobs) == 0 {
+		return append(blocks, slack.NewContextBlock("",
+			slack.NewTextBlockObject(slack.MarkdownType, "No ingestion jobs found.", false, false),
+		))
+	}
+
+	for _, job := range history.Jobs {
+		blocks = append(blocks,
+			slack.NewDividerBlock(),
+			slack.NewSectionBlock(
+				slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s *%s* `%s`\nStarted %s, updated %s",
+					statusEmoji(job.Status), job.Status, job.IngestionJobID,
+					utils.SlackDate(job.StartedAt), utils.SlackDate(job.UpdatedAt)), false, false),
+				nil,
+				nil,
+			),
+			slack.NewContextBlock("",
+				slack.NewTextBlockObject(slack.MarkdownType, job.Statistics, false, false),
+			),
+		)
+	}
+
+	blocks = append(blocks, slack.NewContextBlock("",
+		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("Use `/ragbot-job-status <job_id> %s` for full statistics and failure reasons.", dataSourceID), false, false),
+	))
+
+	if pageKey != "" {
+		blocks = append(blocks, slack.NewActionBlock("job_history_actions",
+			slack.NewButtonBlockElement(ActionJobHistoryNext, fmt.Sprintf("%d:%s:%s", limit, dataSourceID, pageKey),
+				slack.NewTextBlockObject(slack.PlainTextType, "Older jobs", true, false)),
+		))
+	}
+
+	return blocks
+}
+
+// formatIngestionStatistics formats the document counts of an ingestion job, one per line
+func formatIngestionStatistics(stats types.IngestionJobStatistics) string {
+	lines := []string{
+		fmt.Sprintf("  • Documents scanned: %d", stats.DocumentsScanned),
+		fmt.Sprintf("  • New documents indexed: %d", stats.NewDocumentsIndexed),
+		fmt.Sprintf("  • Modified documents indexed: %d", stats.ModifiedDocumentsIndexed),
+		fmt.Sprintf("  • Documents deleted: %d", stats.DocumentsDeleted),
+		fmt.Sprintf("  • Documents failed: %d", stats.DocumentsFailed),
+		fmt.Sprintf("  • Metadata documents scanned: %d", stats.MetadataDocumentsScanned),
+		fmt.Sprintf("  • Metadata documents modified: %d", stats.MetadataDocumentsModified),
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatIngestionFailures lists each failure reason of an ingestion job with the documents it names
+func formatIngestionFailures(failures []types.IngestionFailure) string {
+	if len(failures) == 0 {
+		return "None"
+	}
+
+	var lines []string
+	for i, failure := range failures {
+		lines = append(lines, fmt.Sprintf("  %d. %s", i+1, failure.Reason))
+		for _, document := range failure.Documents {
+			lines = append(lines, "      - "+document)
+		}
+	}
+	return "\n" + strings.Join(lines, "\n")
+}
+
+// scheduleBlocks renders sync schedules with their next and last runs
+func scheduleBlocks(schedules []types.SyncScheduleStatus) []slack.Block {
+	blocks := []slack.Block{titleBlock("🗓️", "Sync schedules")}
+	if len(schedules) == 0 {
+		return append(blocks, contextBlock("No sync schedules are configured. Set RAGBOT_SYNC_SCHEDULES_FILE to add some."))
+	}
+
+	for _, schedule := range schedules {
+		state, emoji := "active", "▶️"
+		if schedule.Paused {
+			state, emoji = "paused", "⏸️"
+		}
+		if schedule.Running {
+			state, emoji = state+", running", "⏳"
+		}
+
+		dataSource := schedule.DataSourceID
+		if dataSource == "" {
+			dataSource = "default"
+		}
+
+		lastRun := "Never"
+		if !schedule.LastRun.IsZero() {
+			lastRun = utils.SlackDate(schedule.LastRun)
+		}
+
+		blocks = append(blocks,
+			slack.NewDividerBlock(),
+			slack.NewSectionBlock(
+				slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s *%s* (%s)", emoji, schedule.Name, state), false, false),
+				nil,
+				nil,
+			),
+		)
+		blocks = append(blocks, fieldBlocks([]blockField{
+			{"Data Source", dataSource},
+			{"Schedule", fmt.Sprintf("`%s` (%s)", schedule.Cron, schedule.Timezone)},
+			{"Next Run", utils.SlackDate(schedule.NextRun)},
+			{"Last Run", lastRun},
+		})...)
+		if schedule.LastResult != "" {
+			blocks = append(blocks, contextBlock(schedule.LastResult))
+		}
+	}
+	return blocks
+}
+
+// formatSyncBatch lists the jobs started by a sync of every data source and the data sources
+// that could not be synced
+func formatSyncBatch(batch types.DataSourceSyncBatch) string {
+	var lines []string
+	for _, job := range batch.Jobs {
+		lines = append(lines, fmt.Sprintf("⏳ %s (%s): job `%s` %s", job.Name, job.DataSourceID, job.IngestionJobID, job.Status))
+	}
+	for _, failure := range batch.Failures {
+		lines = append(lines, fmt.Sprintf("❌ %s (%s): %s", failure.Name, failure.DataSourceID, failure.Error))
+	}
+	if len(lines) == 0 {
+		return "The knowledge base has no data sources."
+	}
+
+	text := strings.Join(lines, "\n")
+	if len(batch.Jobs) > 0 {
+		text += fmt.Sprintf("\n\nA summary will be posted when all %d jobs have finished.", len(batch.Jobs))
+	}
+	return text
+}
+
+// formatSyncResults summarizes the outcome of each job started by a sync of every data source
+func formatSyncResults(jobs []types.DataSourceSync, results []types.MonitorIngestionJobStatus) string {
+	succeeded := 0
+	var lines []string
+	for i, result := range results {
+		status := "✅"
+		if result.Success {
+			succeeded++
+		} else {
+			status = "❌"
+		}
+
+		jobStatus := result.JobStatus
+		if jobStatus == "" {
+			jobStatus = "UNKNOWN"
+		}
+		lines = append(lines, fmt.Sprintf("%s %s (%s): job `%s` %s - %s",
+			status, jobs[i].Name, jobs[i].DataSourceID, result.IngestionJobID, jobStatus, result.Message))
+	}
+
+	return fmt.Sprintf("%d of %d jobs succeeded\n\n%s", succeeded, len(results), strings.Join(lines, "\n"))
+}
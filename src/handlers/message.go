@@ -3,6 +3,8 @@ package handlers
 import (
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
@@ -16,31 +18,40 @@ import (
 type MessageHandler struct {
 	api            *slack.Client
 	bedrockService *services.BedrockService
+	history        *services.HistoryStore
+	directory      *services.SlackDirectory
+	sessions       *services.SessionStore
+	filters        *services.FilterPolicy
+	experiment     *services.AliasExperiment
+	redactor       *services.Redactor
+	audit          *services.AuditLog
+	confirmations  *confirmationStore
+	botUserID      string
+	botUserOnce    sync.Once
 }
 
 // NewMessageHandler creates a new MessageHandler
-func NewMessageHandler(api *slack.Client, bedrockService *services.BedrockService) *MessageHandler {
+func NewMessageHandler(api *slack.Client, bedrockService *services.BedrockService, history *services.HistoryStore, directory *services.SlackDirectory, sessions *services.SessionStore, filters *services.FilterPolicy, experiment *services.AliasExperiment, redactor *services.Redactor, audit *services.AuditLog) *MessageHandler {
 	return &MessageHandler{
 		api:            api,
 		bedrockService: bedrockService,
+		history:        history,
+		directory:      directory,
+		sessions:       sessions,
+		filters:        filters,
+		experiment:     experiment,
+		redactor:       redactor,
+		audit:          audit,
+		confirmations:  newConfirmationStore(),
 	}
 }
 
 // HandleAppMention handles app mention events
 func (h *MessageHandler) HandleAppMention(event *slackevents.AppMentionEvent) {
-	utils.LogInfo(fmt.Sprintf("Processing app mention: %s", event.Text))
+	utils.LogInfo(fmt.Sprintf("Processing app mention: %s", h.redactor.Redact(event.Text).Text))
 
-	// Extract text without the mention
-	mentionPattern := "<@[^>]+>"
-	textAfterMention := event.Text
-
-	// Find mention and remove it
-	if strings.Contains(event.Text, mentionPattern) {
-		parts := strings.SplitN(event.Text, ">", 2)
-		if len(parts) > 1 {
-			textAfterMention = strings.TrimSpace(parts[1])
-		}
-	}
+	// Extract text without the bot mention(s)
+	textAfterMention := utils.StripBotMentions(event.Text, h.getBotUserID())
 
 	// Process the message
 	thread := event.ThreadTimeStamp
@@ -48,7 +59,13 @@ func (h *MessageHandler) HandleAppMention(event *slackevents.AppMentionEvent) {
 		thread = event.TimeStamp
 	}
 
-	h.processMessage(event.Channel, event.TimeStamp, thread, textAfterMention, event.User)
+	// When pulled into an existing thread, send the earlier discussion along as context
+	var sessionContext types.SessionContext
+	if event.ThreadTimeStamp != "" {
+		sessionContext = h.threadSessionContext(event.Channel, event.ThreadTimeStamp, event.TimeStamp)
+	}
+
+	h.processMessage(event.Channel, event.TimeStamp, thread, event.User, utils.ParseMessage(textAfterMention), sessionContext)
 }
 
 // HandleDirectMessage handles direct messages
@@ -59,7 +76,7 @@ func (h *MessageHandler) HandleDirectMessage(event *slackevents.MessageEvent) {
 		return
 	}
 
-	utils.LogInfo(fmt.Sprintf("Processing direct message: %s", event.Text))
+	utils.LogInfo(fmt.Sprintf("Processing direct message: %s", h.redactor.Redact(event.Text).Text))
 
 	// Process the message
 	thread := event.ThreadTimeStamp
@@ -67,7 +84,7 @@ func (h *MessageHandler) HandleDirectMessage(event *slackevents.MessageEvent) {
 		thread = event.TimeStamp
 	}
 
-	h.processMessage(event.Channel, event.TimeStamp, thread, event.Text, event.User)
+	h.processMessage(event.Channel, event.TimeStamp, thread, event.User, utils.ParseMessage(event.Text), types.SessionContext{})
 }
 
 // HandleThreadMessage handles thread messages
@@ -76,23 +93,21 @@ func (h *MessageHandler) HandleThreadMessage(event *slackevents.MessageEvent) {
 	if event.ThreadTimeStamp == "" ||
 	   event.ChannelType == "im" ||
 	   event.BotID != "" ||
-	   event.SubType != "" ||
-	   !strings.HasPrefix(strings.ToLower(event.Text), "hey ragbot") {
+	   event.SubType != "" {
 		return
 	}
 
-	utils.LogInfo(fmt.Sprintf("Processing thread message: %s", event.Text))
+	// Only respond to messages that start with a wake phrase such as "Hey Ragbot"
+	matched, textAfterWakePhrase := utils.MatchWakePhrase(event.Text)
+	if !matched {
+		return
+	}
 
-	// Extract text after "Hey Ragbot"
-	textAfterHeyRagbot := strings.TrimSpace(
-		strings.TrimPrefix(
-			strings.TrimPrefix(event.Text, "Hey Ragbot"),
-			"hey ragbot",
-		),
-	)
+	utils.LogInfo(fmt.Sprintf("Processing thread message: %s", h.redactor.Redact(event.Text).Text))
 
-	// Process the message
-	h.processMessage(event.Channel, event.TimeStamp, event.ThreadTimeStamp, textAfterHeyRagbot, event.User)
+	// Process the message, including the earlier thread discussion if the bot hasn't joined yet
+	sessionContext := h.threadSessionContext(event.Channel, event.ThreadTimeStamp, event.TimeStamp)
+	h.processMessage(event.Channel, event.TimeStamp, event.ThreadTimeStamp, event.User, utils.ParseMessage(textAfterWakePhrase), sessionContext)
 }
 
 // HandleDirectThreadMessage handles thread replies in direct messages that don't need "Hey ragbot" prefix
@@ -105,19 +120,116 @@ func (h *MessageHandler) HandleDirectThreadMessage(event *slackevents.MessageEve
 		return
 	}
 
-	utils.LogInfo(fmt.Sprintf("Processing direct thread message: %s", event.Text))
+	utils.LogInfo(fmt.Sprintf("Processing direct thread message: %s", h.redactor.Redact(event.Text).Text))
 
 	// Process the message directly without requiring "Hey ragbot" prefix
-	h.processMessage(event.Channel, event.TimeStamp, event.ThreadTimeStamp, event.Text, event.User)
+	h.processMessage(event.Channel, event.TimeStamp, event.ThreadTimeStamp, event.User, utils.ParseMessage(event.Text), types.SessionContext{})
+}
+
+// HandleShortcutQuestion handles a question asked through the "Ask Ragbot" shortcut.
+// The question is posted in the thread so others can follow along; when threadTS is
+// empty a new thread is started in the channel.
+func (h *MessageHandler) HandleShortcutQuestion(channel, threadTS, user, question, context string) {
+	utils.LogInfo(fmt.Sprintf("Processing shortcut question from user %s: %s", user, h.redactor.Redact(question).Text))
+
+	_, questionTS, err := h.api.PostMessage(
+		channel,
+		slack.MsgOptionText(fmt.Sprintf("<@%s> asked: %s", user, question), false),
+		slack.MsgOptionTS(threadTS),
+	)
+	if err != nil {
+		utils.LogError(err, "Error posting shortcut question")
+		return
+	}
+
+	if threadTS == "" {
+		threadTS = questionTS
+	}
+
+	// The conversation goes to the agent the same way as earlier thread messages, so only the
+	// question itself is recorded in the asker's history
+	sessionContext := types.SessionContext{}
+	if context != "" {
+		sessionContext.PromptSessionAttributes = map[string]string{threadHistoryAttribute: context}
+	}
+
+	h.processMessage(channel, questionTS, threadTS, user, utils.ParseMessage(question), sessionContext)
+}
+
+// threadHistoryAttribute is the prompt session attribute holding the earlier thread discussion
+const threadHistoryAttribute = "slackThreadHistory"
+
+// threadSessionContext builds the session context holding the prior discussion in a thread
+func (h *MessageHandler) threadSessionContext(channel, threadTS, currentTS string) types.SessionContext {
+	threadHistory := h.priorThreadContext(channel, threadTS, currentTS)
+	if threadHistory == "" {
+		return types.SessionContext{}
+	}
+
+	return types.SessionContext{
+		PromptSessionAttributes: map[string]string{
+			threadHistoryAttribute: threadHistory,
+		},
+	}
 }
 
 // processMessage processes a message and invokes the Bedrock agent
-func (h *MessageHandler) processMessage(channel, timestamp, thread, text, user string) {
+func (h *MessageHandler) processMessage(channel, timestamp, thread, user string, message types.ParsedMessage, sessionContext types.SessionContext) {
 	// Add thinking reaction
 	utils.AddReaction(h.api, channel, timestamp, "thinking_face")
 
-	// Check for traceback flag
-	includeTraceback, inputText := utils.HandleTracebackFlag(text)
+	// Keep secrets and personal data from leaving Slack
+	if !h.redactQuestion(channel, timestamp, user, &message, &sessionContext) {
+		return
+	}
+
+	// Select a different agent alias if requested with --agent=<name>
+	if message.Agent != "" {
+		aliasID, ok := h.bedrockService.ResolveAgentAlias(message.Agent)
+		if !ok {
+			utils.AddReaction(h.api, channel, timestamp, "x")
+			h.reply(channel, timestamp, user, message.Private, fmt.Sprintf(
+				"Unknown agent %q. Available agents: %s", message.Agent, strings.Join(h.bedrockService.AgentAliasNames(), ", ")))
+			return
+		}
+		sessionContext.AgentAliasID = aliasID
+	} else {
+		// Otherwise the thread may be part of the share answered by the candidate alias
+		sessionContext.AgentAliasID = h.experiment.AliasFor(thread)
+	}
+
+	// Start a fresh agent session for this thread if requested with --new-session
+	sessionKey := services.ThreadKey(channel, thread)
+	sessionID := h.sessions.SessionID(sessionKey)
+	if message.NewSession {
+		newSessionID, err := h.ResetThreadSession(channel, thread)
+		if err != nil {
+			utils.LogError(err, "Error saving new session")
+		}
+		sessionID = newSessionID
+		if message.Text == "" {
+			utils.AddReaction(h.api, channel, timestamp, "white_check_mark")
+			h.reply(channel, timestamp, user, message.Private, "Started a new session for this thread.")
+			return
+		}
+	}
+
+	// Remember the alias the session talks to, so resetting the thread ends it there
+	if err := h.sessions.Use(sessionKey, sessionContext.AgentAliasID); err != nil {
+		utils.LogError(err, "Error saving session alias")
+	}
+
+	// Tell the agent who is asking and where, for personalized answers and action group authorization
+	h.addIdentityAttributes(&sessionContext, channel, user)
+
+	// Limit the knowledge base to the documents this channel and user may see
+	sessionContext.KnowledgeBaseFilters = h.filters.Resolve(channel, user)
+
+	// Return the raw knowledge base passages instead of an agent answer if requested
+	if message.RetrieveOnly {
+		h.sendRetrieveRequest(channel, timestamp, user, message, sessionContext.KnowledgeBaseFilters)
+		return
+	}
 
 	// Retrieve any file attachments
 	var fileAttachments []types.FileAttachment
@@ -126,13 +238,34 @@ func (h *MessageHandler) processMessage(channel, timestamp, thread, text, user s
 	// This is a simplified version since we don't have direct access to files in events API
 	// In a real implementation, you would need to retrieve files from the event
 
+	// Ask the user in Slack before running agent actions that need their approval
+	sessionContext.ConfirmAction = h.actionConfirmer(channel, timestamp, user, message.Private)
+
 	// Get response from Bedrock with any attachments
-	h.sendAgentRequest(channel, timestamp, thread, inputText, fileAttachments, includeTraceback)
+	h.sendAgentRequest(channel, timestamp, thread, sessionID, user, message, fileAttachments, sessionContext)
+}
+
+// addIdentityAttributes adds the Slack user and channel to the session attributes, which
+// action groups receive, and to the prompt session attributes used in prompt templates
+func (h *MessageHandler) addIdentityAttributes(sessionContext *types.SessionContext, channel, user string) {
+	identity := h.directory.IdentityAttributes(user, channel)
+
+	if sessionContext.SessionAttributes == nil {
+		sessionContext.SessionAttributes = map[string]string{}
+	}
+	if sessionContext.PromptSessionAttributes == nil {
+		sessionContext.PromptSessionAttributes = map[string]string{}
+	}
+	for key, value := range identity {
+		sessionContext.SessionAttributes[key] = value
+		sessionContext.PromptSessionAttributes[key] = value
+	}
 }
 
 // sendAgentRequest sends a request to the Bedrock agent and handles the response
-func (h *MessageHandler) sendAgentRequest(channel, timestamp, thread, inputText string, attachments []types.FileAttachment, includeTraceback bool) {
+func (h *MessageHandler) sendAgentRequest(channel, timestamp, thread, sessionID, user string, message types.ParsedMessage, attachments []types.FileAttachment, sessionContext types.SessionContext) {
 	hasAttachments := len(attachments) > 0
+	inputText := message.Text
 
 	// Append attachment notice to input if needed
 	fullInput := inputText
@@ -140,37 +273,136 @@ func (h *MessageHandler) sendAgentRequest(channel, timestamp, thread, inputText
 		fullInput = fullInput + " use these files when generating your answer"
 	}
 
-	// Get response from Bedrock
-	response, err := h.bedrockService.InvokeBedrockAgent(fullInput, thread, attachments, includeTraceback)
+	// Get response from Bedrock, recording which alias answered and how long it took
+	outcome := types.AnswerOutcome{
+		AnswerID: channel + ":" + timestamp,
+		AliasID:  h.experiment.AnsweringAlias(sessionContext.AgentAliasID),
+		Channel:  channel,
+		ThreadTS: thread,
+		User:     user,
+	}
+	start := time.Now()
+	response, err := h.bedrockService.Answer(fullInput, sessionID, attachments, message.Traceback, sessionContext)
+	outcome.LatencyMs = time.Since(start).Milliseconds()
 	if err != nil {
 		utils.LogError(err, "Error invoking Bedrock agent")
 		utils.AddReaction(h.api, channel, timestamp, "x")
-		utils.SendSlackMessage(h.api, channel, "Error invoking Bedrock agent: "+err.Error(), timestamp)
+		h.reply(channel, timestamp, user, message.Private, "Error invoking Bedrock agent: "+err.Error())
+		h.recordHistory(user, inputText, err.Error(), outcome)
 		return
 	}
 
 	// Check if the response is an error
 	if errorResp, ok := response.(types.ErrorResponse); ok {
 		utils.AddReaction(h.api, channel, timestamp, "x")
-		utils.SendSlackMessage(h.api, channel, "Error invoking Bedrock agent: "+errorResp.Error, timestamp)
+		h.reply(channel, timestamp, user, message.Private, "Error invoking Bedrock agent: "+errorResp.Error)
+		h.recordHistory(user, inputText, errorResp.Error, outcome)
+		return
+	}
+
+	// Explain a question or answer blocked by a guardrail instead of posting the agent's
+	// response. Blocks are kept apart from answers and errors when comparing aliases.
+	if agentResp, ok := response.(types.AgentResponse); ok && agentResp.Guardrail != nil {
+		outcome.Blocked = true
+		utils.LogWarning(fmt.Sprintf("Guardrail blocked the %s of a question from user %s in channel %s: %s",
+			agentResp.Guardrail.Stage, user, channel, services.FormatGuardrailPolicies(agentResp.Guardrail.Policies)))
+		h.recordGuardrailIntervention(channel, user, sessionID, agentResp.Guardrail)
+
+		explanation := GuardrailMessage(agentResp.Guardrail)
+		utils.AddReaction(h.api, channel, timestamp, "no_entry_sign")
+		h.reply(channel, timestamp, user, message.Private, explanation)
+		if agentResp.Traceback != "" {
+			h.reply(channel, timestamp, user, message.Private, agentResp.Traceback)
+		}
+		h.recordHistory(user, inputText, explanation, outcome)
 		return
 	}
 
 	// Handle successful response
+	outcome.Success = true
 	utils.AddReaction(h.api, channel, timestamp, "white_check_mark")
 
 	// Format the response based on type
-	var responseText string
+	var responseText, tracebackText string
 	if agentResp, ok := response.(types.AgentResponse); ok {
-		responseText = agentResp.Response
-		if agentResp.Traceback != "" {
-			responseText += "\n\n" + agentResp.Traceback
-		}
+		responseText = agentResp.Response + FormatCitations(agentResp.Citations)
+		tracebackText = agentResp.Traceback
 	} else if stringResp, ok := response.(string); ok {
 		responseText = stringResp
 	} else {
 		responseText = fmt.Sprintf("%v", response)
 	}
 
-	utils.SendSlackMessage(h.api, channel, responseText, timestamp)
+	// The traceback is posted as its own message so long answers and tracebacks split cleanly
+	h.reply(channel, timestamp, user, message.Private, responseText, feedbackBlock(outcome))
+	if tracebackText != "" {
+		h.reply(channel, timestamp, user, message.Private, tracebackText)
+	}
+	h.recordHistory(user, inputText, responseText, outcome)
+}
+
+// sendRetrieveRequest queries the knowledge base directly and posts the matching passages
+func (h *MessageHandler) sendRetrieveRequest(channel, timestamp, user string, message types.ParsedMessage, filters []types.MetadataFilter) {
+	response, err := h.bedrockService.RetrieveFromKnowledgeBase(message.Text, services.DefaultRetrieveResults, filters)
+	if err != nil {
+		utils.LogError(err, "Error retrieving from knowledge base")
+		utils.AddReaction(h.api, channel, timestamp, "x")
+		h.reply(channel, timestamp, user, message.Private, "Error retrieving from knowledge base: "+err.Error())
+		return
+	}
+
+	if errorResp, ok := response.(types.ErrorResponse); ok {
+		utils.AddReaction(h.api, channel, timestamp, "x")
+		h.reply(channel, timestamp, user, message.Private, "Error retrieving from knowledge base: "+errorResp.Error)
+		return
+	}
+
+	results, ok := response.(types.RetrievalResults)
+	if !ok {
+		h.reply(channel, timestamp, user, message.Private, fmt.Sprintf("%v", response))
+		return
+	}
+
+	utils.AddReaction(h.api, channel, timestamp, "white_check_mark")
+
+	blocks := retrievalBlocks(results)
+	fallback := fmt.Sprintf("%d knowledge base results for: %s", len(results.Results), results.Query)
+	if message.Private {
+		err = utils.SendEphemeralBlocks(h.api, channel, user, fallback, timestamp, blocks)
+	} else {
+		err = utils.SendSlackBlocks(h.api, channel, fallback, timestamp, blocks)
+	}
+	if err != nil {
+		utils.LogError(err, "Error sending retrieval results")
+	}
+}
+
+// reply posts a response in the message's thread, visible only to the user when private is
+// set. Any extra blocks are added below the response.
+func (h *MessageHandler) reply(channel, timestamp, user string, private bool, text string, extra ...slack.Block) {
+	var err error
+	if private {
+		err = utils.SendEphemeralMessage(h.api, channel, user, text, timestamp, extra...)
+	} else {
+		err = utils.SendSlackMessage(h.api, channel, text, timestamp, extra...)
+	}
+
+	if err != nil {
+		utils.LogError(err, "Error sending response")
+	}
+}
+
+// recordHistory stores a question and its answer in the user's history for the App Home tab
+// and records the outcome of the answer for comparing agent aliases
+func (h *MessageHandler) recordHistory(user, question, answer string, outcome types.AnswerOutcome) {
+	h.experiment.RecordAnswer(outcome)
+	h.history.Add(user, types.HistoryEntry{
+		Question: question,
+		Answer:   answer,
+		Channel:  outcome.Channel,
+		ThreadTS: outcome.ThreadTS,
+		Success:  outcome.Success,
+		AskedAt:  time.Now(),
+		AliasID:  outcome.AliasID,
+	})
 }
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+
+	"slack-rag-server/src/services"
+	"slack-rag-server/src/types"
+	"slack-rag-server/src/utils"
+)
+
+// redactQuestion applies the channel's redaction mode to a question and the thread discussion
+// sent along with it. Sensitive data is masked, or the question is refused with a warning to
+// the asker in block mode. Returns false when the question must not be sent.
+func (h *MessageHandler) redactQuestion(channel, timestamp, user string, message *types.ParsedMessage, sessionContext *types.SessionContext) bool {
+	mode := h.redactor.Mode(channel)
+	if mode == services.RedactionAllow {
+		return true
+	}
+
+	// Earlier messages in the thread were written by others, so they are masked in either mode
+	if history, ok := sessionContext.PromptSessionAttributes[threadHistoryAttribute]; ok {
+		redacted := h.redactor.Redact(history)
+		if len(redacted.Detections) > 0 {
+			utils.LogWarning(fmt.Sprintf("Masked thread history in channel %s: %s", channel, services.SummarizeDetections(redacted.Detections)))
+			sessionContext.PromptSessionAttributes[threadHistoryAttribute] = redacted.Text
+		}
+	}
+
+	redacted := h.redactor.Redact(message.Text)
+	if len(redacted.Detections) == 0 {
+		return true
+	}
+
+	if mode == services.RedactionBlock {
+		utils.LogWarning(fmt.Sprintf("Blocked question from user %s in channel %s: %s", user, channel, services.SummarizeDetections(redacted.Detections)))
+		utils.AddReaction(h.api, channel, timestamp, "no_entry")
+		h.reply(channel, timestamp, user, true, fmt.Sprintf(
+			"I didn't send your question because it looks like it contains sensitive data (%s). Please remove it and ask again. If it is a real secret, delete your message and rotate it.",
+			h.redactor.DescribeDetections(redacted.Detections)))
+		return false
+	}
+
+	utils.LogWarning(fmt.Sprintf("Masked question from user %s in channel %s: %s", user, channel, services.SummarizeDetections(redacted.Detections)))
+	message.Text = redacted.Text
+	return true
+}
@@ -0,0 +1,544 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+
+	"slack-rag-server/src/services"
+	"slack-rag-server/src/utils"
+)
+
+// CommandName is the unified slash command. Every subcommand is also registered in Slack as
+// a legacy alias named "/ragbot-<subcommand>", e.g. "/ragbot-job-status".
+const CommandName = "/ragbot"
+
+// legacyCommandPrefix is the prefix of the legacy per-subcommand slash commands
+const legacyCommandPrefix = CommandName + "-"
+
+// commandPermission controls who may run a command
+type commandPermission int
+
+const (
+	permissionEveryone commandPermission = iota
+	permissionMaintainer
+)
+
+// commandArg describes a positional argument of a command
+type commandArg struct {
+	Name     string
+	Required bool
+	// Rest collects every remaining word, so it must be the last argument
+	Rest bool
+	// Validate checks a value. An optional argument whose value fails validation is skipped
+	// in favor of the next argument, so "[n] [data source]" accepts either or both.
+	Validate func(value string) error
+}
+
+// commandFlag describes a --flag of a command
+type commandFlag struct {
+	Name string
+	// Value is the placeholder of the flag's value ("N"), or empty for a boolean flag
+	Value    string
+	Repeated bool
+	Help     string
+	Validate func(value string) error
+}
+
+// commandSpec declares a subcommand of /ragbot
+type commandSpec struct {
+	// Name is the subcommand, one or two words ("job-status", "schedule pause")
+	Name string
+	// Aliases are other names of the subcommand
+	Aliases    []string
+	Args       []commandArg
+	Flags      []commandFlag
+	Permission commandPermission
+	// InChannel posts the response to the channel by default instead of only to the user.
+	// Any command can be made public with --public.
+	InChannel bool
+	Help      string
+	Run       func(cmd slack.SlashCommand, args commandArgs)
+}
+
+// commandArgs holds the parsed and validated arguments and flags of a command
+type commandArgs struct {
+	values map[string]string
+	flags  map[string][]string
+	public bool
+}
+
+// Arg returns a positional argument, or "" when it was not given
+func (a commandArgs) Arg(name string) string {
+	return a.values[name]
+}
+
+// Int returns a positional argument as a number, or fallback when it was not given
+func (a commandArgs) Int(name string, fallback int) int {
+	value, err := strconv.Atoi(a.values[name])
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// Flag returns the last value of a flag, or "" when it was not given
+func (a commandArgs) Flag(name string) string {
+	values := a.flags[name]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// Flags returns every value of a repeated flag
+func (a commandArgs) Flags(name string) []string {
+	return a.flags[name]
+}
+
+// Has checks whether a flag was given
+func (a commandArgs) Has(name string) bool {
+	_, ok := a.flags[name]
+	return ok
+}
+
+// commandSpecs declares every subcommand, in the order they are listed by help
+func (h *CommandHandler) commandSpecs() []commandSpec {
+	dataSource := commandArg{Name: "data source"}
+
+	return []commandSpec{
+		{
+			Name: "help",
+			Args: []commandArg{{Name: "command", Rest: true}},
+			Help: "Show this help message, or the details of a command",
+			Run:  h.HandleHelp,
+		},
+		{
+			Name: "kb-status",
+			Help: "Check the status of the knowledge base",
+			Run:  h.HandleKbStatus,
+		},
+		{
+			Name:       "sync-datasource",
+			Args:       []commandArg{dataSource},
+			Flags:      []commandFlag{{Name: "all", Help: "Sync every data source in the knowledge base"}},
+			InChannel:  true,
+			Permission: permissionMaintainer,
+			Help:       "Trigger a sync of a data source, or of every data source",
+			Run:        h.HandleSyncDataSource,
+		},
+		{
+			Name: "list-datasources",
+			Help: "List all available data sources",
+			Run:  h.HandleListDataSources,
+		},
+		{
+			Name: "ds-config",
+			Args: []commandArg{dataSource},
+			Help: "Get configuration for a data source",
+			Run:  h.HandleDsConfig,
+		},
+		{
+			Name: "get-datasource",
+			Args: []commandArg{dataSource},
+			Help: "Get information about a data source",
+			Run:  h.HandleGetDataSource,
+		},
+		{
+			Name: "agent-status",
+			Help: "Check the status of the agent",
+			Run:  h.HandleAgentStatus,
+		},
+		{
+			Name: "agent versions",
+			Help: "List the versions of the agent and the aliases serving them",
+			Run:  h.HandleAgentVersions,
+		},
+		{
+			Name: "agent aliases",
+			Help: "List the aliases of the agent and the versions they serve",
+			Run:  h.HandleAgentAliases,
+		},
+		{
+			Name:       "agent prepare",
+			Permission: permissionMaintainer,
+			InChannel:  true,
+			Help:       "Prepare the DRAFT version of the agent and wait until it is ready",
+			Run:        h.HandleAgentPrepare,
+		},
+		{
+			Name:       "agent promote",
+			Args:       []commandArg{{Name: "alias", Required: true}, {Name: "version", Validate: validateAgentVersion}},
+			Permission: permissionMaintainer,
+			InChannel:  true,
+			Help:       "Point an alias at a version (a new version from DRAFT when omitted)",
+			Run:        h.HandleAgentPromote,
+		},
+		{
+			Name: "agent experiment",
+			Help: "Compare latency, error rate and feedback between the agent aliases answering questions",
+			Run:  h.HandleAgentExperiment,
+		},
+		{
+			Name: "job-status",
+			Args: []commandArg{{Name: "job_id", Required: true}, dataSource},
+			Help: "Check the status of an ingestion job",
+			Run:  h.HandleJobStatus,
+		},
+		{
+			Name: "job-history",
+			Args: []commandArg{{Name: "n", Validate: intBetween(1, services.MaxJobHistorySize)}, dataSource},
+			Help: "List the most recent ingestion jobs",
+			Run:  h.HandleJobHistory,
+		},
+		{
+			Name: "health-check",
+			Help: "Check overall health of the Bedrock agent service",
+			Run:  h.HandleHealthCheck,
+		},
+		{
+			Name: "search",
+			Args: []commandArg{{Name: "query", Required: true, Rest: true}},
+			Flags: []commandFlag{
+				{Name: "top-k", Value: "N", Help: "Number of passages to return", Validate: intBetween(1, services.MaxRetrieveResults)},
+				{Name: "filter", Value: "key=value", Repeated: true, Help: "Metadata filter: key=value, key=a|b or key^=prefix", Validate: validateMetadataFilter},
+			},
+			Help: "Search the knowledge base without invoking the agent",
+			Run:  h.HandleSearch,
+		},
+		{
+			Name:    "schedule",
+			Aliases: []string{"schedule list"},
+			Help:    "Show scheduled data source syncs",
+			Run:     h.HandleSchedule,
+		},
+		{
+			Name:       "schedule pause",
+			Args:       []commandArg{{Name: "name", Required: true}},
+			Permission: permissionMaintainer,
+			InChannel:  true,
+			Help:       "Stop a schedule from starting syncs",
+			Run:        h.HandleSchedulePause,
+		},
+		{
+			Name:       "schedule resume",
+			Args:       []commandArg{{Name: "name", Required: true}},
+			Permission: permissionMaintainer,
+			InChannel:  true,
+			Help:       "Let a paused schedule start syncs again",
+			Run:        h.HandleScheduleResume,
+		},
+		{
+			Name: "reset",
+			Args: []commandArg{{Name: "thread link"}},
+			Help: "Start a new agent session for a thread (defaults to your latest thread in this channel)",
+			Run:  h.HandleReset,
+		},
+	}
+}
+
+// Dispatch runs the subcommand named by a /ragbot or legacy /ragbot-<subcommand> slash command
+func (h *CommandHandler) Dispatch(cmd slack.SlashCommand) {
+	var words []string
+	switch {
+	case cmd.Command == CommandName:
+	case strings.HasPrefix(cmd.Command, legacyCommandPrefix):
+		words = []string{strings.TrimPrefix(cmd.Command, legacyCommandPrefix)}
+	default:
+		utils.LogWarning(fmt.Sprintf("Unknown command: %s", cmd.Command))
+		return
+	}
+
+	tokens, err := tokenizeCommand(cmd.Text)
+	if err != nil {
+		h.respondToCommand(cmd, "Error: "+err.Error())
+		return
+	}
+	words = append(words, tokens...)
+
+	if len(words) == 0 {
+		words = []string{"help"}
+	}
+
+	spec, rest := h.findCommand(words)
+	if spec == nil {
+		h.respondToCommand(cmd, fmt.Sprintf("Unknown command `%s`. Use `%s help` to see all commands.", words[0], CommandName))
+		return
+	}
+
+	if spec.Permission == permissionMaintainer && !utils.IsMaintainer(cmd.UserID) {
+		h.respondToCommand(cmd, fmt.Sprintf("Sorry, only Ragbot maintainers can use `%s %s`.", CommandName, spec.Name))
+		return
+	}
+
+	args, err := parseCommandArgs(spec, rest)
+	if err != nil {
+		h.respondToCommand(cmd, fmt.Sprintf("Error: %v\nUsage: %s", err, commandUsage(spec)))
+		return
+	}
+
+	h.setResponseType(cmd, spec.InChannel || args.public)
+	defer h.clearResponseType(cmd)
+
+	utils.LogInfo(fmt.Sprintf("Running command %s %s for user %s", CommandName, spec.Name, cmd.UserID))
+	spec.Run(cmd, args)
+}
+
+// findCommand finds the subcommand named by the first words, preferring two-word names, and
+// returns it with the remaining words
+func (h *CommandHandler) findCommand(words []string) (*commandSpec, []string) {
+	for _, count := range []int{2, 1} {
+		if len(words) < count {
+			continue
+		}
+		name := strings.ToLower(strings.Join(words[:count], " "))
+		for i := range h.commands {
+			spec := &h.commands[i]
+			if spec.Name == name || containsString(spec.Aliases, name) {
+				return spec, words[count:]
+			}
+		}
+	}
+	return nil, nil
+}
+
+// parseCommandArgs matches words against the arguments and flags of a command
+func parseCommandArgs(spec *commandSpec, words []string) (commandArgs, error) {
+	args := commandArgs{values: map[string]string{}, flags: map[string][]string{}}
+
+	var positional []string
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if word == "--" {
+			positional = append(positional, words[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(word, "--") {
+			positional = append(positional, word)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(word, "--"), "=")
+		if name == "public" && !hasValue {
+			args.public = true
+			continue
+		}
+
+		flag := findFlag(spec, name)
+		if flag == nil {
+			return args, fmt.Errorf("unknown flag --%s", name)
+		}
+
+		switch {
+		case flag.Value == "" && hasValue:
+			return args, fmt.Errorf("--%s does not take a value", name)
+		case flag.Value != "" && !hasValue:
+			if i+1 >= len(words) {
+				return args, fmt.Errorf("--%s needs a value (--%s=%s)", name, name, flag.Value)
+			}
+			i++
+			value = words[i]
+		}
+
+		if _, seen := args.flags[name]; seen && !flag.Repeated {
+			return args, fmt.Errorf("--%s can only be given once", name)
+		}
+		if flag.Validate != nil {
+			if err := flag.Validate(value); err != nil {
+				return args, fmt.Errorf("invalid --%s: %w", name, err)
+			}
+		}
+		args.flags[name] = append(args.flags[name], value)
+	}
+
+	next := 0
+	for i, arg := range spec.Args {
+		if next >= len(positional) {
+			if arg.Required {
+				return args, fmt.Errorf("missing <%s>", arg.Name)
+			}
+			continue
+		}
+
+		value := positional[next]
+		if arg.Rest {
+			value = strings.Join(positional[next:], " ")
+		}
+
+		if err := validateArg(arg, value); err != nil {
+			// Let an optional argument be left out when the value suits a later one
+			if !arg.Required && i < len(spec.Args)-1 {
+				continue
+			}
+			return args, fmt.Errorf("invalid <%s>: %w", arg.Name, err)
+		}
+
+		args.values[arg.Name] = value
+		if arg.Rest {
+			next = len(positional)
+		} else {
+			next++
+		}
+	}
+
+	if next < len(positional) {
+		return args, fmt.Errorf("unexpected argument %q", positional[next])
+	}
+
+	return args, nil
+}
+
+// validateArg checks a value with the argument's validation, if it has one
+func validateArg(arg commandArg, value string) error {
+	if arg.Validate == nil {
+		return nil
+	}
+	return arg.Validate(value)
+}
+
+// findFlag finds a flag of a command by name
+func findFlag(spec *commandSpec, name string) *commandFlag {
+	for i := range spec.Flags {
+		if spec.Flags[i].Name == name {
+			return &spec.Flags[i]
+		}
+	}
+	return nil
+}
+
+// tokenizeCommand splits command text into words, keeping quoted phrases together. Slack's
+// curly quotes are accepted as well as straight ones.
+func tokenizeCommand(text string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inToken := false
+	var closing rune
+
+	for _, r := range text {
+		switch {
+		case closing != 0:
+			if r == closing {
+				closing = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '“':
+			closing = '"'
+			if r == '“' {
+				closing = '”'
+			}
+			inToken = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+
+	if closing != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+// commandUsage formats the usage line of a command, e.g. "/ragbot job-status <job_id> [data source]"
+func commandUsage(spec *commandSpec) string {
+	parts := []string{CommandName, spec.Name}
+	for _, flag := range spec.Flags {
+		usage := "--" + flag.Name
+		if flag.Value != "" {
+			usage += "=" + flag.Value
+		}
+		if flag.Repeated {
+			usage += "..."
+		}
+		parts = append(parts, "["+usage+"]")
+	}
+	for _, arg := range spec.Args {
+		name := arg.Name
+		if arg.Rest && !arg.Required {
+			name += "..."
+		}
+		if arg.Required {
+			parts = append(parts, "<"+name+">")
+		} else {
+			parts = append(parts, "["+name+"]")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// commandHelpBlocks renders the detailed help of a command
+func commandHelpBlocks(spec *commandSpec) []slack.Block {
+	blocks := []slack.Block{
+		titleBlock("📖", commandUsage(spec)),
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, spec.Help, false, false), nil, nil),
+	}
+
+	var flags []string
+	for _, flag := range spec.Flags {
+		flags = append(flags, fmt.Sprintf("• `--%s` - %s", flag.Name, flag.Help))
+	}
+	flags = append(flags, "• `--public` - Post the response to the channel")
+	blocks = append(blocks, textBlocks("*Flags*\n"+strings.Join(flags, "\n"))...)
+
+	notes := []string{"Legacy alias: `" + legacyCommandPrefix + spec.Name + "`"}
+	if len(spec.Aliases) > 0 {
+		notes = append(notes, "Also available as: "+strings.Join(spec.Aliases, ", "))
+	}
+	if spec.InChannel {
+		notes = append(notes, "Responses are posted to the channel")
+	} else {
+		notes = append(notes, "Responses are only visible to you")
+	}
+	if spec.Permission == permissionMaintainer {
+		notes = append(notes, "Only Ragbot maintainers can use this command")
+	}
+	return append(blocks, contextBlock(strings.Join(notes, " · ")))
+}
+
+// intBetween validates that a value is a whole number in a range
+func intBetween(min, max int) func(string) error {
+	return func(value string) error {
+		number, err := strconv.Atoi(value)
+		if err != nil || number < min || number > max {
+			return fmt.Errorf("%q must be a number between %d and %d", value, min, max)
+		}
+		return nil
+	}
+}
+
+// validateAgentVersion validates that a value is a numbered agent version, the only kind an
+// alias can serve
+func validateAgentVersion(value string) error {
+	if number, err := strconv.Atoi(value); err != nil || number < 1 {
+		return fmt.Errorf("%q is not a numbered agent version", value)
+	}
+	return nil
+}
+
+// validateMetadataFilter validates a --filter value
+func validateMetadataFilter(value string) error {
+	_, err := utils.ParseMetadataFilter(value)
+	return err
+}
+
+// containsString checks whether a list contains a value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
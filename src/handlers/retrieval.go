@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/slack-go/slack"
+
+	"slack-rag-server/src/types"
+	"slack-rag-server/src/utils"
+)
+
+// retrievalPassageLength is the maximum length of a passage shown in search results
+const retrievalPassageLength = 1500
+
+// retrievalBlocks renders knowledge base passages as Block Kit results with scores and sources
+func retrievalBlocks(results types.RetrievalResults) []slack.Block {
+	blocks := []slack.Block{
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*Knowledge base results for:* %s", results.Query), false, false),
+			nil,
+			nil,
+		),
+	}
+
+	if len(results.Results) == 0 {
+		return append(blocks, slack.NewContextBlock("",
+			slack.NewTextBlockObject(slack.MarkdownType, "No matching passages found.", false, false),
+		))
+	}
+
+	for i, result := range results.Results {
+		if !fitsMessage(blocks, 3) {
+			blocks = append(blocks, contextBlock(fmt.Sprintf("%d more passages not shown.", len(results.Results)-i)))
+			break
+		}
+
+		passage := utils.TruncateText(strings.TrimSpace(result.Text), retrievalPassageLength)
+
+		blocks = append(blocks,
+			slack.NewDividerBlock(),
+			slack.NewSectionBlock(
+				slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*%d.* %s", i+1, passage), false, false),
+				nil,
+				nil,
+			),
+			slack.NewContextBlock("",
+				slack.NewTextBlockObject(slack.MarkdownType, retrievalContext(result), false, false),
+			),
+		)
+	}
+
+	return blocks
+}
+
+// retrievalContext renders the score, source link and metadata of a passage
+func retrievalContext(result types.RetrievalResult) string {
+	parts := []string{fmt.Sprintf("Score: %.3f", result.Score)}
+
+	if result.Source != "" {
+		if strings.HasPrefix(result.Source, "http") {
+			parts = append(parts, fmt.Sprintf("Source: <%s>", result.Source))
+		} else {
+			parts = append(parts, "Source: `"+result.Source+"`")
+		}
+	}
+
+	var keys []string
+	for key := range result.Metadata {
+		if !strings.HasPrefix(key, "x-amz-bedrock-kb-") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s: %v", key, result.Metadata[key]))
+	}
+
+	return strings.Join(parts, " | ")
+}
+
+// FormatCitations renders the unique sources cited in an answer as a markdown list
+func FormatCitations(citations []types.Citation) string {
+	seen := map[string]bool{}
+	var lines []string
+	for _, citation := range citations {
+		for _, source := range citation.Sources {
+			if seen[source] {
+				continue
+			}
+			seen[source] = true
+			lines = append(lines, "- "+source)
+		}
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+	return "\n\n**Sources:**\n" + strings.Join(lines, "\n")
+}
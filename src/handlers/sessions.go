@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"slack-rag-server/src/services"
+	"slack-rag-server/src/utils"
+)
+
+// resetSession ends the agent session of a thread and assigns the thread a new one
+func resetSession(bedrockService *services.BedrockService, sessions *services.SessionStore, channel, thread string) (string, error) {
+	key := services.ThreadKey(channel, thread)
+	oldSessionID := sessions.SessionID(key)
+	if err := bedrockService.EndSession(oldSessionID, sessions.AliasID(key)); err != nil {
+		// The old session expires on its own, so carry on with the new one
+		utils.LogWarning(fmt.Sprintf("Could not end session %s: %v", oldSessionID, err))
+	}
+
+	sessionID, err := sessions.Rotate(key)
+	if err != nil {
+		return sessionID, err
+	}
+
+	utils.LogInfo(fmt.Sprintf("Rotated session for thread %s from %s to %s", key, oldSessionID, sessionID))
+	return sessionID, nil
+}
+
+// ResetThreadSession starts a new agent session for a thread
+func (h *MessageHandler) ResetThreadSession(channel, thread string) (string, error) {
+	return resetSession(h.bedrockService, h.sessions, channel, thread)
+}
+
+// askedInThread checks whether a thread is among the recent questions of a user
+func askedInThread(history *services.HistoryStore, user, channel, thread string) bool {
+	for _, entry := range history.Recent(user, 0) {
+		if entry.Channel == channel && entry.ThreadTS == thread {
+			return true
+		}
+	}
+	return false
+}
+
+// parseThreadLink extracts the channel and thread timestamp from a Slack message link such as
+// https://example.slack.com/archives/C0123/p1700000000123456?thread_ts=1700000000.123456
+func parseThreadLink(link string) (channel, thread string, ok bool) {
+	link = strings.Trim(link, "<>")
+	if target, _, found := strings.Cut(link, "|"); found {
+		link = target
+	}
+
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return "", "", false
+	}
+
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "archives" || !strings.HasPrefix(parts[2], "p") || len(parts[2]) <= 7 {
+		return "", "", false
+	}
+	channel = parts[1]
+
+	if threadTS := parsed.Query().Get("thread_ts"); threadTS != "" {
+		return channel, threadTS, true
+	}
+
+	// Message links encode the timestamp without its dot, e.g. p1700000000123456
+	digits := strings.TrimPrefix(parts[2], "p")
+	return channel, digits[:len(digits)-6] + "." + digits[len(digits)-6:], true
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/slack-go/slack"
+
+	"slack-rag-server/src/utils"
+)
+
+// Callback IDs for the "Ask Ragbot" shortcuts and modal
+const (
+	CallbackAskMessageShortcut = "ask_ragbot_message"
+	CallbackAskGlobalShortcut  = "ask_ragbot"
+	CallbackAskModal           = "ask_ragbot_modal"
+)
+
+// Block and action IDs used by the "Ask Ragbot" modal
+const (
+	askQuestionBlockID  = "question_block"
+	askQuestionActionID = "question_input"
+	askThreadBlockID    = "thread_block"
+	askThreadActionID   = "thread_checkbox"
+	askChannelBlockID   = "channel_block"
+	askChannelActionID  = "channel_select"
+)
+
+// askModalMetadata is carried in the modal's private_metadata between opening and submission
+type askModalMetadata struct {
+	Channel   string `json:"channel,omitempty"`
+	MessageTS string `json:"messageTs,omitempty"`
+	ThreadTS  string `json:"threadTs,omitempty"`
+}
+
+// handleShortcut opens the "Ask Ragbot" modal for a message or global shortcut
+func (h *InteractionHandler) handleShortcut(callback slack.InteractionCallback) {
+	var metadata askModalMetadata
+	if callback.Type == slack.InteractionTypeMessageAction {
+		threadTS := callback.Message.ThreadTimestamp
+		if threadTS == "" {
+			threadTS = callback.Message.Timestamp
+		}
+		metadata = askModalMetadata{
+			Channel:   callback.Channel.ID,
+			MessageTS: callback.Message.Timestamp,
+			ThreadTS:  threadTS,
+		}
+	}
+
+	metadataBytes, err := json.Marshal(metadata)
+	if err != nil {
+		utils.LogError(err, "Error marshalling modal metadata")
+		return
+	}
+
+	view := slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		CallbackID:      CallbackAskModal,
+		Title:           slack.NewTextBlockObject(slack.PlainTextType, "Ask Ragbot", false, false),
+		Submit:          slack.NewTextBlockObject(slack.PlainTextType, "Ask", false, false),
+		Close:           slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		PrivateMetadata: string(metadataBytes),
+		Blocks:          slack.Blocks{BlockSet: askModalBlocks(metadata)},
+	}
+
+	if _, err := h.api.OpenView(callback.TriggerID, view); err != nil {
+		utils.LogError(err, "Error opening Ask Ragbot modal")
+	}
+}
+
+// askModalBlocks builds the modal inputs; global shortcuts also ask where to post the answer
+func askModalBlocks(metadata askModalMetadata) []slack.Block {
+	questionInput := slack.NewPlainTextInputBlockElement(
+		slack.NewTextBlockObject(slack.PlainTextType, "What would you like to know?", false, false),
+		askQuestionActionID,
+	)
+	questionInput.Multiline = true
+
+	blocks := []slack.Block{
+		slack.NewInputBlock(askQuestionBlockID,
+			slack.NewTextBlockObject(slack.PlainTextType, "Question", false, false),
+			nil,
+			questionInput,
+		),
+	}
+
+	if metadata.MessageTS == "" {
+		channelSelect := slack.NewOptionsSelectBlockElement(
+			slack.OptTypeConversations,
+			slack.NewTextBlockObject(slack.PlainTextType, "Select a conversation", false, false),
+			askChannelActionID,
+		)
+		channelSelect.DefaultToCurrentConversation = true
+
+		return append(blocks, slack.NewInputBlock(askChannelBlockID,
+			slack.NewTextBlockObject(slack.PlainTextType, "Post the answer in", false, false),
+			nil,
+			channelSelect,
+		))
+	}
+
+	threadOption := slack.NewCheckboxGroupsBlockElement(askThreadActionID,
+		slack.NewOptionBlockObject("include_thread",
+			slack.NewTextBlockObject(slack.PlainTextType, "Include the whole thread as context", false, false),
+			nil,
+		),
+	)
+	threadBlock := slack.NewInputBlock(askThreadBlockID,
+		slack.NewTextBlockObject(slack.PlainTextType, "Context", false, false),
+		nil,
+		threadOption,
+	)
+	threadBlock.Optional = true
+
+	return append(blocks, threadBlock)
+}
+
+// handleAskModalSubmission gathers the selected message (or thread) and asks the agent in that thread
+func (h *InteractionHandler) handleAskModalSubmission(callback slack.InteractionCallback) {
+	var metadata askModalMetadata
+	if err := json.Unmarshal([]byte(callback.View.PrivateMetadata), &metadata); err != nil {
+		utils.LogError(err, "Error parsing modal metadata")
+		return
+	}
+
+	values := map[string]map[string]slack.BlockAction{}
+	if callback.View.State != nil {
+		values = callback.View.State.Values
+	}
+
+	question := strings.TrimSpace(values[askQuestionBlockID][askQuestionActionID].Value)
+	if question == "" {
+		return
+	}
+
+	userID := callback.User.ID
+
+	// Global shortcuts have no message context, so start a new thread in the selected conversation
+	if metadata.MessageTS == "" {
+		channel := values[askChannelBlockID][askChannelActionID].SelectedConversation
+		if channel == "" {
+			channel = userID
+		}
+		h.messageHandler.HandleShortcutQuestion(channel, "", userID, question, "")
+		return
+	}
+
+	includeThread := len(values[askThreadBlockID][askThreadActionID].SelectedOptions) > 0
+
+	messages, err := utils.GetThreadMessages(h.api, metadata.Channel, metadata.ThreadTS)
+	if err != nil {
+		utils.LogError(err, "Error fetching messages for shortcut")
+		utils.SendSlackMessage(h.api, userID, "Sorry, I couldn't read that conversation. Make sure Ragbot has been added to the channel.", "")
+		return
+	}
+
+	if !includeThread {
+		var selected []slack.Message
+		for _, message := range messages {
+			if message.Timestamp == metadata.MessageTS {
+				selected = append(selected, message)
+			}
+		}
+		messages = selected
+	}
+
+	h.messageHandler.HandleShortcutQuestion(metadata.Channel, metadata.ThreadTS, userID, question, h.messageHandler.formatThreadContext(messages))
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"slack-rag-server/src/types"
+)
+
+// defaultActionTimeout is how long a local action may run when RAGBOT_ACTION_TIMEOUT_SECONDS is not set
+const defaultActionTimeout = 30 * time.Second
+
+// maxActionResponseLength is the largest action result sent back to the agent
+const maxActionResponseLength = 20000
+
+// ActionHandler runs an action the agent returned control for and returns the result
+// text sent back to the agent
+type ActionHandler func(ctx context.Context, invocation types.ActionInvocation) (string, error)
+
+// httpActionConfig is an entry of RAGBOT_ACTIONS_FILE that forwards an action to an HTTP endpoint.
+// Parameters are substituted into the URL as {name} and sent as a JSON body for non-GET requests.
+type httpActionConfig struct {
+	ActionGroup string            `json:"actionGroup"`
+	Function    string            `json:"function"`
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`
+	Headers     map[string]string `json:"headers"`
+}
+
+// ActionRegistry holds the local handlers for agent action groups that return control to the caller
+type ActionRegistry struct {
+	mu       sync.RWMutex
+	handlers map[string]ActionHandler
+	timeout  time.Duration
+	audit    *AuditLog
+}
+
+// NewActionRegistry creates a new ActionRegistry, registering any HTTP actions from
+// the JSON file named in RAGBOT_ACTIONS_FILE
+func NewActionRegistry(audit *AuditLog) (*ActionRegistry, error) {
+	timeout := defaultActionTimeout
+	if value := os.Getenv("RAGBOT_ACTION_TIMEOUT_SECONDS"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			timeout = time.Duration(parsed) * time.Second
+		}
+	}
+
+	registry := &ActionRegistry{
+		handlers: make(map[string]ActionHandler),
+		timeout:  timeout,
+		audit:    audit,
+	}
+
+	path := os.Getenv("RAGBOT_ACTIONS_FILE")
+	if path == "" {
+		return registry, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read actions file: %w", err)
+	}
+
+	var configs []httpActionConfig
+	if err := json.Unmarshal(content, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse actions file: %w", err)
+	}
+
+	for _, config := range configs {
+		if config.ActionGroup == "" || config.Function == "" || config.URL == "" {
+			return nil, fmt.Errorf("action in actions file must have an actionGroup, function and url")
+		}
+		registry.Register(config.ActionGroup, config.Function, httpAction(config))
+	}
+
+	return registry, nil
+}
+
+// Register adds a handler for an action. The name is the function name for function-schema
+// action groups, or "METHOD /path" (e.g. "GET /tickets/{id}") for OpenAPI action groups.
+func (r *ActionRegistry) Register(actionGroup, name string, handler ActionHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[actionKey(actionGroup, name)] = handler
+}
+
+// Has checks whether a handler is registered for an invocation
+func (r *ActionRegistry) Has(invocation types.ActionInvocation) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.handlers[actionKey(invocation.ActionGroup, ActionName(invocation))]
+	return ok
+}
+
+// Execute runs the handler registered for an invocation with the configured timeout,
+// recording the outcome and the user and channel it was run for in the audit trail
+func (r *ActionRegistry) Execute(sessionID, user, channel string, invocation types.ActionInvocation) (string, error) {
+	name := ActionName(invocation)
+
+	r.mu.RLock()
+	handler, ok := r.handlers[actionKey(invocation.ActionGroup, name)]
+	r.mu.RUnlock()
+
+	start := time.Now()
+	var result string
+	var err error
+	if !ok {
+		err = fmt.Errorf("no local handler is registered for %s/%s", invocation.ActionGroup, name)
+	} else {
+		result, err = r.run(handler, invocation)
+	}
+
+	entry := types.AuditEntry{
+		Event:       "action_executed",
+		SessionID:   sessionID,
+		User:        user,
+		Channel:     channel,
+		ActionGroup: invocation.ActionGroup,
+		Action:      name,
+		Parameters:  invocation.Parameters,
+		Result:      result,
+		DurationMs:  time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		entry.Event = "action_failed"
+		entry.Error = err.Error()
+	}
+	r.audit.Record(entry)
+
+	return result, err
+}
+
+// RecordConfirmation records the decision of the user asked to approve an action in the audit trail
+func (r *ActionRegistry) RecordConfirmation(sessionID, user, channel string, invocation types.ActionInvocation, approved bool, decision string) {
+	if r == nil {
+		return
+	}
+
+	event := "action_denied"
+	if approved {
+		event = "action_approved"
+	}
+	r.audit.Record(types.AuditEntry{
+		Event:       event,
+		SessionID:   sessionID,
+		User:        user,
+		Channel:     channel,
+		ActionGroup: invocation.ActionGroup,
+		Action:      ActionName(invocation),
+		Parameters:  invocation.Parameters,
+		Result:      decision,
+	})
+}
+
+// run calls a handler, giving up once the timeout has passed
+func (r *ActionRegistry) run(handler ActionHandler, invocation types.ActionInvocation) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	type outcome struct {
+		result string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := handler(ctx, invocation)
+		done <- outcome{result: result, err: err}
+	}()
+
+	select {
+	case out := <-done:
+		return out.result, out.err
+	case <-ctx.Done():
+		return "", fmt.Errorf("action timed out after %s", r.timeout)
+	}
+}
+
+// ActionName returns the registry name of an invocation: the function name, or
+// "METHOD /path" for OpenAPI action groups
+func ActionName(invocation types.ActionInvocation) string {
+	if invocation.Function != "" {
+		return invocation.Function
+	}
+	return strings.ToUpper(invocation.HTTPMethod) + " " + invocation.APIPath
+}
+
+// actionKey builds the registry key for an action
+func actionKey(actionGroup, name string) string {
+	return actionGroup + "/" + name
+}
+
+// httpAction creates a handler that forwards an action to an HTTP endpoint
+func httpAction(config httpActionConfig) ActionHandler {
+	method := strings.ToUpper(config.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	return func(ctx context.Context, invocation types.ActionInvocation) (string, error) {
+		target := config.URL
+		for name, value := range invocation.Parameters {
+			target = strings.ReplaceAll(target, "{"+name+"}", url.PathEscape(value))
+		}
+
+		var body io.Reader
+		if method != http.MethodGet {
+			payload, err := json.Marshal(invocation.Parameters)
+			if err != nil {
+				return "", err
+			}
+			body = bytes.NewReader(payload)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, target, body)
+		if err != nil {
+			return "", err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		for key, value := range config.Headers {
+			req.Header.Set(key, os.ExpandEnv(value))
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		content, err := io.ReadAll(io.LimitReader(resp.Body, maxActionResponseLength))
+		if err != nil {
+			return "", err
+		}
+		if resp.StatusCode >= 300 {
+			return "", fmt.Errorf("%s %s returned status %d: %s", method, target, resp.StatusCode, strings.TrimSpace(string(content)))
+		}
+
+		return string(content), nil
+	}
+}
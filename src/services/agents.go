@@ -0,0 +1,325 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	bedrockagent "github.com/aws/aws-sdk-go-v2/service/bedrockagent"
+	bedrockagent_types "github.com/aws/aws-sdk-go-v2/service/bedrockagent/types"
+
+	"slack-rag-server/src/types"
+)
+
+// DraftAgentVersion is the working version of an agent that PrepareAgent prepares
+const DraftAgentVersion = "DRAFT"
+
+// agentPollInterval is how often agent and alias preparation is checked
+const agentPollInterval = 10 * time.Second
+
+// ListAgentVersions lists the versions of the agent, newest first
+func (s *BedrockService) ListAgentVersions() (interface{}, error) {
+	if s.agentID == "" {
+		return types.ErrorResponse{
+			Error: "Agent ID is not configured",
+		}, nil
+	}
+
+	versions := []types.AgentVersion{}
+	paginator := bedrockagent.NewListAgentVersionsPaginator(s.agentClient, &bedrockagent.ListAgentVersionsInput{
+		AgentId: aws.String(s.agentID),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return types.ErrorResponse{
+				Error:         err.Error(),
+				OriginalError: err,
+			}, nil
+		}
+
+		for _, version := range resp.AgentVersionSummaries {
+			versions = append(versions, types.AgentVersion{
+				Version:     aws.ToString(version.AgentVersion),
+				Name:        aws.ToString(version.AgentName),
+				Status:      string(version.AgentStatus),
+				Description: aws.ToString(version.Description),
+				CreatedAt:   aws.ToTime(version.CreatedAt),
+				UpdatedAt:   aws.ToTime(version.UpdatedAt),
+				RawResponse: version,
+			})
+		}
+	}
+
+	// Numbered versions newest first, with DRAFT at the top
+	sort.Slice(versions, func(i, j int) bool {
+		return agentVersionOrder(versions[i].Version) > agentVersionOrder(versions[j].Version)
+	})
+
+	return types.AgentVersionList{
+		Versions: versions,
+		Count:    len(versions),
+	}, nil
+}
+
+// agentVersionOrder orders agent versions numerically, with DRAFT after every numbered version
+func agentVersionOrder(version string) int {
+	if version == DraftAgentVersion {
+		return int(^uint(0) >> 1)
+	}
+	number, _ := strconv.Atoi(version)
+	return number
+}
+
+// ListAgentAliases lists the aliases of the agent, ordered by name
+func (s *BedrockService) ListAgentAliases() (interface{}, error) {
+	if s.agentID == "" {
+		return types.ErrorResponse{
+			Error: "Agent ID is not configured",
+		}, nil
+	}
+
+	aliases := []types.AgentAlias{}
+	paginator := bedrockagent.NewListAgentAliasesPaginator(s.agentClient, &bedrockagent.ListAgentAliasesInput{
+		AgentId: aws.String(s.agentID),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return types.ErrorResponse{
+				Error:         err.Error(),
+				OriginalError: err,
+			}, nil
+		}
+
+		for _, alias := range resp.AgentAliasSummaries {
+			aliases = append(aliases, types.AgentAlias{
+				AliasID:     aws.ToString(alias.AgentAliasId),
+				Name:        aws.ToString(alias.AgentAliasName),
+				Status:      string(alias.AgentAliasStatus),
+				Description: aws.ToString(alias.Description),
+				Versions:    routedVersions(alias.RoutingConfiguration),
+				CreatedAt:   aws.ToTime(alias.CreatedAt),
+				UpdatedAt:   aws.ToTime(alias.UpdatedAt),
+				RawResponse: alias,
+			})
+		}
+	}
+
+	sort.Slice(aliases, func(i, j int) bool {
+		return strings.ToLower(aliases[i].Name) < strings.ToLower(aliases[j].Name)
+	})
+
+	return types.AgentAliasList{
+		Aliases: aliases,
+		Count:   len(aliases),
+	}, nil
+}
+
+// routedVersions returns the agent versions an alias routes to
+func routedVersions(routing []bedrockagent_types.AgentAliasRoutingConfigurationListItem) []string {
+	versions := []string{}
+	for _, route := range routing {
+		if route.AgentVersion != nil {
+			versions = append(versions, *route.AgentVersion)
+		}
+	}
+	return versions
+}
+
+// FindAgentAlias returns the alias of the agent with the given name or ID
+func (s *BedrockService) FindAgentAlias(nameOrID string) (types.AgentAlias, error) {
+	response, err := s.ListAgentAliases()
+	if err != nil {
+		return types.AgentAlias{}, err
+	}
+	if errorResp, ok := response.(types.ErrorResponse); ok {
+		return types.AgentAlias{}, fmt.Errorf("%s", errorResp.Error)
+	}
+	list, ok := response.(types.AgentAliasList)
+	if !ok {
+		return types.AgentAlias{}, fmt.Errorf("unexpected response: %v", response)
+	}
+
+	var names []string
+	for _, alias := range list.Aliases {
+		if alias.AliasID == nameOrID || strings.EqualFold(alias.Name, nameOrID) {
+			return alias, nil
+		}
+		names = append(names, fmt.Sprintf("`%s` (%s)", alias.Name, alias.AliasID))
+	}
+
+	if len(names) == 0 {
+		return types.AgentAlias{}, fmt.Errorf("unknown alias %q, the agent has no aliases", nameOrID)
+	}
+	return types.AgentAlias{}, fmt.Errorf("unknown alias %q. Available aliases: %s", nameOrID, strings.Join(names, ", "))
+}
+
+// PrepareAgent prepares the DRAFT version of the agent so that it can be tested and versioned
+func (s *BedrockService) PrepareAgent() (interface{}, error) {
+	if s.agentID == "" {
+		return types.ErrorResponse{
+			Error: "Agent ID is not configured",
+		}, nil
+	}
+
+	resp, err := s.agentClient.PrepareAgent(context.Background(), &bedrockagent.PrepareAgentInput{
+		AgentId: aws.String(s.agentID),
+	})
+	if err != nil {
+		return types.ErrorResponse{
+			Error:         err.Error(),
+			OriginalError: err,
+		}, nil
+	}
+
+	return types.AgentPreparation{
+		AgentID:    aws.ToString(resp.AgentId),
+		Status:     string(resp.AgentStatus),
+		Version:    aws.ToString(resp.AgentVersion),
+		PreparedAt: aws.ToTime(resp.PreparedAt),
+		Message:    "Agent preparation started",
+	}, nil
+}
+
+// MonitorAgentPreparation polls the agent until it is PREPARED or preparation fails
+func (s *BedrockService) MonitorAgentPreparation(maxWaitMinutes int) (interface{}, error) {
+	timeout := time.Now().Add(time.Duration(maxWaitMinutes) * time.Minute)
+
+	for time.Now().Before(timeout) {
+		response, err := s.GetAgentStatus()
+		if err != nil {
+			return types.ErrorResponse{
+				Error:         fmt.Sprintf("Failed to get agent status: %v", err),
+				OriginalError: err,
+			}, nil
+		}
+		if errorResp, ok := response.(types.ErrorResponse); ok {
+			return errorResp, nil
+		}
+
+		if agentStatus, ok := response.(types.AgentStatus); ok {
+			switch agentStatus.AgentStatus {
+			case "PREPARED":
+				return types.AgentPreparation{
+					Success:    true,
+					AgentID:    agentStatus.AgentID,
+					Status:     agentStatus.AgentStatus,
+					Version:    DraftAgentVersion,
+					PreparedAt: agentStatus.UpdatedAt,
+					Message:    "Agent is prepared and ready to test",
+				}, nil
+			case "FAILED", "NOT_PREPARED":
+				return types.AgentPreparation{
+					AgentID: agentStatus.AgentID,
+					Status:  agentStatus.AgentStatus,
+					Version: DraftAgentVersion,
+					Message: "Agent preparation failed, check the agent configuration in the Bedrock console",
+				}, nil
+			}
+		}
+
+		time.Sleep(agentPollInterval)
+	}
+
+	return types.ErrorResponse{
+		Error: fmt.Sprintf("Agent preparation monitoring timed out after %d minutes", maxWaitMinutes),
+	}, nil
+}
+
+// UpdateAgentAlias points an alias of the agent at a version. Without a version, Bedrock
+// creates a new version from the prepared DRAFT and points the alias at it.
+func (s *BedrockService) UpdateAgentAlias(aliasID, version string) (interface{}, error) {
+	if s.agentID == "" {
+		return types.ErrorResponse{
+			Error: "Agent ID is not configured",
+		}, nil
+	}
+
+	// The alias name and description are required, so keep the current ones
+	current, err := s.agentClient.GetAgentAlias(context.Background(), &bedrockagent.GetAgentAliasInput{
+		AgentId:      aws.String(s.agentID),
+		AgentAliasId: aws.String(aliasID),
+	})
+	if err != nil {
+		return types.ErrorResponse{
+			Error:         err.Error(),
+			OriginalError: err,
+		}, nil
+	}
+
+	input := &bedrockagent.UpdateAgentAliasInput{
+		AgentId:        aws.String(s.agentID),
+		AgentAliasId:   aws.String(aliasID),
+		AgentAliasName: current.AgentAlias.AgentAliasName,
+		Description:    current.AgentAlias.Description,
+	}
+	if version != "" {
+		input.RoutingConfiguration = []bedrockagent_types.AgentAliasRoutingConfigurationListItem{
+			{AgentVersion: aws.String(version)},
+		}
+	}
+
+	resp, err := s.agentClient.UpdateAgentAlias(context.Background(), input)
+	if err != nil {
+		return types.ErrorResponse{
+			Error:         err.Error(),
+			OriginalError: err,
+		}, nil
+	}
+
+	return types.AgentPreparation{
+		AgentID: s.agentID,
+		AliasID: aws.ToString(resp.AgentAlias.AgentAliasId),
+		Status:  string(resp.AgentAlias.AgentAliasStatus),
+		Version: strings.Join(routedVersions(resp.AgentAlias.RoutingConfiguration), ", "),
+		Message: "Alias update started",
+	}, nil
+}
+
+// MonitorAgentAlias polls an alias of the agent until it is PREPARED or its update fails
+func (s *BedrockService) MonitorAgentAlias(aliasID string, maxWaitMinutes int) (interface{}, error) {
+	timeout := time.Now().Add(time.Duration(maxWaitMinutes) * time.Minute)
+
+	for time.Now().Before(timeout) {
+		resp, err := s.agentClient.GetAgentAlias(context.Background(), &bedrockagent.GetAgentAliasInput{
+			AgentId:      aws.String(s.agentID),
+			AgentAliasId: aws.String(aliasID),
+		})
+		if err != nil {
+			return types.ErrorResponse{
+				Error:         fmt.Sprintf("Failed to get alias status: %v", err),
+				OriginalError: err,
+			}, nil
+		}
+
+		alias := resp.AgentAlias
+		preparation := types.AgentPreparation{
+			AgentID:    s.agentID,
+			AliasID:    aliasID,
+			Status:     string(alias.AgentAliasStatus),
+			Version:    strings.Join(routedVersions(alias.RoutingConfiguration), ", "),
+			PreparedAt: aws.ToTime(alias.UpdatedAt),
+		}
+
+		switch alias.AgentAliasStatus {
+		case bedrockagent_types.AgentAliasStatusPrepared:
+			preparation.Success = true
+			preparation.Message = fmt.Sprintf("Alias %s now serves version %s", aws.ToString(alias.AgentAliasName), preparation.Version)
+			return preparation, nil
+		case bedrockagent_types.AgentAliasStatusFailed:
+			preparation.Message = "Alias update failed: " + strings.Join(alias.FailureReasons, "; ")
+			return preparation, nil
+		}
+
+		time.Sleep(agentPollInterval)
+	}
+
+	return types.ErrorResponse{
+		Error: fmt.Sprintf("Alias monitoring timed out after %d minutes", maxWaitMinutes),
+	}, nil
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"slack-rag-server/src/types"
+	"slack-rag-server/src/utils"
+)
+
+// AuditLog records actions taken on behalf of users as JSON lines. Entries are appended to
+// the file named in RAGBOT_AUDIT_LOG, or written to the application log when it is not set.
+type AuditLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewAuditLog creates a new AuditLog
+func NewAuditLog() (*AuditLog, error) {
+	audit := &AuditLog{}
+
+	path := os.Getenv("RAGBOT_AUDIT_LOG")
+	if path == "" {
+		return audit, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	audit.file = file
+
+	return audit, nil
+}
+
+// Record appends an entry to the audit trail
+func (a *AuditLog) Record(entry types.AuditEntry) {
+	if a == nil {
+		return
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		utils.LogError(err, "Error encoding audit entry")
+		return
+	}
+
+	if a.file == nil {
+		utils.LogInfo("[AUDIT] " + string(line))
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		utils.LogError(err, "Error writing audit entry")
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	bedrockagentruntime "github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime"
+	bedrockagentruntime_types "github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime/types"
+
+	"slack-rag-server/src/types"
+	"slack-rag-server/src/utils"
+)
+
+// Backend names accepted in RAGBOT_BACKEND
+const (
+	BackendAgent               = "agent"
+	BackendRetrieveAndGenerate = "retrieve_and_generate"
+	BackendFake                = "fake"
+)
+
+// AnswerBackend produces answers to user questions. Answers are returned as
+// types.AgentResponse, or types.ErrorResponse when the backend reports an error. Sessions are
+// ended on the agent alias they talk to, empty for the configured alias.
+type AnswerBackend interface {
+	Name() string
+	Answer(inputText, sessionID string, attachments []types.FileAttachment, includeTraceback bool, sessionContext types.SessionContext) (interface{}, error)
+	EndSession(sessionID, aliasID string) error
+}
+
+// agentBackend answers questions with a Bedrock agent via InvokeAgent
+type agentBackend struct {
+	service *BedrockService
+}
+
+// Name returns the backend name
+func (b *agentBackend) Name() string {
+	return BackendAgent
+}
+
+// Answer invokes the configured Bedrock agent
+func (b *agentBackend) Answer(inputText, sessionID string, attachments []types.FileAttachment, includeTraceback bool, sessionContext types.SessionContext) (interface{}, error) {
+	return b.service.InvokeBedrockAgent(inputText, sessionID, attachments, includeTraceback, sessionContext)
+}
+
+// EndSession ends the agent session so Bedrock discards its conversation
+func (b *agentBackend) EndSession(sessionID, aliasID string) error {
+	return b.service.EndAgentSession(sessionID, aliasID)
+}
+
+// retrieveAndGenerateBackend answers questions directly from the knowledge base via
+// RetrieveAndGenerate, for knowledge bases that don't have an agent in front of them
+type retrieveAndGenerateBackend struct {
+	service        *BedrockService
+	modelArn       string
+	promptTemplate string
+
+	// Bedrock generates RetrieveAndGenerate session IDs itself, so keep track of
+	// which Bedrock session belongs to each of our session IDs
+	mu       sync.Mutex
+	sessions map[string]bedrockSession
+}
+
+// retrieveAndGenerateSessionTTL is how long an idle RetrieveAndGenerate session is remembered.
+// Bedrock expires its sessions after 24 hours, so older ones cannot be continued anyway.
+const retrieveAndGenerateSessionTTL = 24 * time.Hour
+
+// bedrockSession is the Bedrock session of one of our session IDs and when it was last used
+type bedrockSession struct {
+	id     string
+	usedAt time.Time
+}
+
+// newRetrieveAndGenerateBackend creates a RetrieveAndGenerate backend from the environment
+func newRetrieveAndGenerateBackend(service *BedrockService) (*retrieveAndGenerateBackend, error) {
+	if service.knowledgeBaseID == "" {
+		return nil, fmt.Errorf("AWS_BEDROCK_KNOWLEDGE_BASE_ID environment variable is required for the %s backend", BackendRetrieveAndGenerate)
+	}
+
+	modelArn := os.Getenv("RAGBOT_RAG_MODEL_ARN")
+	if modelArn == "" {
+		return nil, fmt.Errorf("RAGBOT_RAG_MODEL_ARN environment variable is required for the %s backend", BackendRetrieveAndGenerate)
+	}
+
+	promptTemplate := os.Getenv("RAGBOT_RAG_PROMPT_TEMPLATE")
+	if path := os.Getenv("RAGBOT_RAG_PROMPT_TEMPLATE_FILE"); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt template file: %w", err)
+		}
+		promptTemplate = string(content)
+	}
+
+	return &retrieveAndGenerateBackend{
+		service:        service,
+		modelArn:       modelArn,
+		promptTemplate: promptTemplate,
+		sessions:       make(map[string]bedrockSession),
+	}, nil
+}
+
+// Name returns the backend name
+func (b *retrieveAndGenerateBackend) Name() string {
+	return BackendRetrieveAndGenerate
+}
+
+// Answer generates an answer from the knowledge base, continuing the Bedrock session
+// previously used for the same session ID
+func (b *retrieveAndGenerateBackend) Answer(inputText, sessionID string, attachments []types.FileAttachment, includeTraceback bool, sessionContext types.SessionContext) (interface{}, error) {
+	if len(attachments) > 0 {
+		utils.LogWarning("File attachments are not supported by the retrieve_and_generate backend. Ignoring attachments.")
+	}
+
+	knowledgeBaseConfig := &bedrockagentruntime_types.KnowledgeBaseRetrieveAndGenerateConfiguration{
+		KnowledgeBaseId: aws.String(b.service.knowledgeBaseID),
+		ModelArn:        aws.String(b.modelArn),
+	}
+	if len(sessionContext.KnowledgeBaseFilters) > 0 {
+		retrievalConfig, err := b.service.retrievalConfiguration(0, sessionContext.KnowledgeBaseFilters)
+		if err != nil {
+			return types.ErrorResponse{
+				Error:         err.Error(),
+				OriginalError: err,
+			}, nil
+		}
+		knowledgeBaseConfig.RetrievalConfiguration = retrievalConfig
+	}
+	if b.promptTemplate != "" {
+		knowledgeBaseConfig.GenerationConfiguration = &bedrockagentruntime_types.GenerationConfiguration{
+			PromptTemplate: &bedrockagentruntime_types.PromptTemplate{
+				TextPromptTemplate: aws.String(b.promptTemplate),
+			},
+		}
+	}
+
+	input := &bedrockagentruntime.RetrieveAndGenerateInput{
+		Input: &bedrockagentruntime_types.RetrieveAndGenerateInput{
+			Text: aws.String(inputText),
+		},
+		RetrieveAndGenerateConfiguration: &bedrockagentruntime_types.RetrieveAndGenerateConfiguration{
+			Type:                       bedrockagentruntime_types.RetrieveAndGenerateTypeKnowledgeBase,
+			KnowledgeBaseConfiguration: knowledgeBaseConfig,
+		},
+	}
+
+	bedrockSessionID, hasSession := b.session(sessionID)
+	if hasSession {
+		input.SessionId = aws.String(bedrockSessionID)
+	}
+
+	output, err := b.service.agentRuntimeClient.RetrieveAndGenerate(context.Background(), input)
+	if err != nil && hasSession && isExpiredSessionError(err) {
+		utils.LogWarning(fmt.Sprintf("RetrieveAndGenerate session of %s is no longer valid, retrying with a new session: %v", sessionID, err))
+		input.SessionId = nil
+		output, err = b.service.agentRuntimeClient.RetrieveAndGenerate(context.Background(), input)
+	}
+	if err != nil {
+		return types.ErrorResponse{
+			Error:         err.Error(),
+			OriginalError: err,
+		}, nil
+	}
+
+	if output.SessionId != nil {
+		b.saveSession(sessionID, *output.SessionId)
+	}
+
+	responseText := ""
+	if output.Output != nil && output.Output.Text != nil {
+		responseText = *output.Output.Text
+	}
+	if responseText == "" {
+		responseText = fmt.Sprintf("Queried knowledge base successfully with session ID: %s, but received no response text.", sessionID)
+	}
+
+	response := types.AgentResponse{
+		Response:  responseText,
+		Citations: convertCitations(output.Citations),
+	}
+	if includeTraceback {
+		response.Traceback = fmt.Sprintf("```\nBackend: %s\nModel: %s\nBedrock session: %s\nCitations: %d\n```",
+			BackendRetrieveAndGenerate, b.modelArn, aws.ToString(output.SessionId), len(output.Citations))
+	}
+
+	return response, nil
+}
+
+// EndSession forgets the Bedrock session used for a session ID, so the next question starts a new one
+func (b *retrieveAndGenerateBackend) EndSession(sessionID, aliasID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.sessions, sessionID)
+	return nil
+}
+
+// session returns the Bedrock session used for a session ID, unless it has been idle too long
+func (b *retrieveAndGenerateBackend) session(sessionID string) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	saved, ok := b.sessions[sessionID]
+	if !ok || time.Since(saved.usedAt) > retrieveAndGenerateSessionTTL {
+		return "", false
+	}
+	return saved.id, true
+}
+
+// saveSession remembers the Bedrock session used for a session ID, dropping idle sessions
+func (b *retrieveAndGenerateBackend) saveSession(sessionID, bedrockSessionID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key, saved := range b.sessions {
+		if time.Since(saved.usedAt) > retrieveAndGenerateSessionTTL {
+			delete(b.sessions, key)
+		}
+	}
+	b.sessions[sessionID] = bedrockSession{id: bedrockSessionID, usedAt: time.Now()}
+}
+
+// isExpiredSessionError checks whether RetrieveAndGenerate rejected a request because its
+// session no longer exists, as opposed to throttling or other failures not worth retrying
+func isExpiredSessionError(err error) bool {
+	var validation *bedrockagentruntime_types.ValidationException
+	return errors.As(err, &validation) && strings.Contains(strings.ToLower(validation.ErrorMessage()), "session")
+}
+
+// convertCitations converts Bedrock citations into answer citations with their source locations
+func convertCitations(citations []bedrockagentruntime_types.Citation) []types.Citation {
+	result := []types.Citation{}
+	for _, citation := range citations {
+		converted := types.Citation{Sources: []string{}}
+		if citation.GeneratedResponsePart != nil && citation.GeneratedResponsePart.TextResponsePart != nil {
+			converted.Text = aws.ToString(citation.GeneratedResponsePart.TextResponsePart.Text)
+		}
+		for _, reference := range citation.RetrievedReferences {
+			if source := retrievalSource(reference.Location); source != "" {
+				converted.Sources = append(converted.Sources, source)
+			}
+		}
+		result = append(result, converted)
+	}
+	return result
+}
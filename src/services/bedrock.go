@@ -2,17 +2,22 @@ package services
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	bedrockagent "github.com/aws/aws-sdk-go-v2/service/bedrockagent"
+	bedrockagent_types "github.com/aws/aws-sdk-go-v2/service/bedrockagent/types"
 	bedrockagentruntime "github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime"
 	bedrockagentruntime_types "github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime/types"
 
 	"slack-rag-server/src/types"
+	"slack-rag-server/src/utils"
 )
 
 // BedrockService provides methods for interacting with AWS Bedrock
@@ -24,13 +29,23 @@ type BedrockService struct {
 	agentAliasID      string
 	knowledgeBaseID   string
 	dataSourceID      string
+	agentAliases      map[string]string
+	backend           AnswerBackend
+	actions           *ActionRegistry
+	guardrail         *guardrailChecker
 }
 
 // NewBedrockService creates a new BedrockService
 func NewBedrockService() (*BedrockService, error) {
-	// Get AWS region from environment
+	// Select the answering backend, defaulting to the Bedrock agent
+	backendName := os.Getenv("RAGBOT_BACKEND")
+	if backendName == "" {
+		backendName = BackendAgent
+	}
+
+	// Get AWS region from environment; the fake backend works without AWS
 	region := os.Getenv("AWS_BEDROCK_REGION")
-	if region == "" {
+	if region == "" && backendName != BackendFake {
 		return nil, fmt.Errorf("AWS_BEDROCK_REGION environment variable is not set")
 	}
 
@@ -46,22 +61,28 @@ func NewBedrockService() (*BedrockService, error) {
 	// Create Bedrock agent runtime client
 	agentRuntimeClient := bedrockagentruntime.NewFromConfig(cfg)
 
-	// Get required IDs from environment
+	// Agent IDs are only required when answering with an agent
 	agentID := os.Getenv("AWS_BEDROCK_AGENT_ID")
-	if agentID == "" {
+	if agentID == "" && backendName == BackendAgent {
 		return nil, fmt.Errorf("AWS_BEDROCK_AGENT_ID environment variable is not set")
 	}
 
 	agentAliasID := os.Getenv("AWS_BEDROCK_AGENT_ALIAS_ID")
-	if agentAliasID == "" {
+	if agentAliasID == "" && backendName == BackendAgent {
 		return nil, fmt.Errorf("AWS_BEDROCK_AGENT_ALIAS_ID environment variable is not set")
 	}
 
+	// Check questions and answers with a standalone guardrail if one is configured
+	guardrail, err := newGuardrailChecker(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// Knowledge base and data source IDs are optional
 	knowledgeBaseID := os.Getenv("AWS_BEDROCK_KNOWLEDGE_BASE_ID")
 	dataSourceID := os.Getenv("AWS_BEDROCK_DATA_SOURCE_ID")
 
-	return &BedrockService{
+	service := &BedrockService{
 		agentClient:       agentClient,
 		agentRuntimeClient: agentRuntimeClient,
 		region:            region,
@@ -69,7 +90,120 @@ func NewBedrockService() (*BedrockService, error) {
 		agentAliasID:      agentAliasID,
 		knowledgeBaseID:   knowledgeBaseID,
 		dataSourceID:      dataSourceID,
-	}, nil
+		agentAliases:      parseAgentAliases(os.Getenv("RAGBOT_AGENT_ALIASES")),
+		guardrail:         guardrail,
+	}
+
+	switch backendName {
+	case BackendAgent:
+		service.backend = &agentBackend{service: service}
+	case BackendRetrieveAndGenerate:
+		backend, err := newRetrieveAndGenerateBackend(service)
+		if err != nil {
+			return nil, err
+		}
+		service.backend = backend
+	case BackendFake:
+		backend, err := newFakeBackend()
+		if err != nil {
+			return nil, err
+		}
+		service.backend = backend
+	default:
+		return nil, fmt.Errorf("unknown RAGBOT_BACKEND %q, expected %s, %s or %s", backendName, BackendAgent, BackendRetrieveAndGenerate, BackendFake)
+	}
+
+	return service, nil
+}
+
+// Answer answers a question using the configured backend (agent or RetrieveAndGenerate)
+func (s *BedrockService) Answer(inputText, sessionID string, attachments []types.FileAttachment, includeTraceback bool, sessionContext types.SessionContext) (interface{}, error) {
+	// Check the question with the standalone guardrail before it reaches the backend
+	intervention, err := s.guardrail.check(inputText, GuardrailStageInput)
+	if err != nil {
+		return types.ErrorResponse{
+			Error:         err.Error(),
+			OriginalError: err,
+		}, nil
+	}
+	if intervention != nil {
+		return types.AgentResponse{Guardrail: intervention}, nil
+	}
+
+	// Don't stream an answer that may still be withheld by the output check
+	if s.guardrail.checksOutput() {
+		sessionContext.OnChunk = nil
+	}
+
+	response, err := s.backend.Answer(inputText, sessionID, attachments, includeTraceback, sessionContext)
+	if err != nil {
+		return response, err
+	}
+
+	// Check the answer too, unless the backend's own guardrail already blocked it
+	answer, ok := response.(types.AgentResponse)
+	if !ok || answer.Guardrail != nil {
+		return response, nil
+	}
+	intervention, err = s.guardrail.check(answer.Response, GuardrailStageOutput)
+	if err != nil {
+		return types.ErrorResponse{
+			Error:         err.Error(),
+			OriginalError: err,
+		}, nil
+	}
+	if intervention != nil {
+		// Withhold the blocked answer so it isn't shown or kept in the history
+		answer.Response = ""
+		answer.Citations = nil
+		answer.Guardrail = intervention
+	}
+
+	return answer, nil
+}
+
+// SetActionRegistry sets the local handlers used when the agent returns control
+func (s *BedrockService) SetActionRegistry(actions *ActionRegistry) {
+	s.actions = actions
+}
+
+// EndSession ends a conversation session with the configured backend, on the agent alias the
+// session talks to (empty for the configured alias)
+func (s *BedrockService) EndSession(sessionID, aliasID string) error {
+	return s.backend.EndSession(sessionID, aliasID)
+}
+
+// BackendName returns the name of the configured answering backend
+func (s *BedrockService) BackendName() string {
+	return s.backend.Name()
+}
+
+// parseAgentAliases parses a comma-separated list of name=aliasId pairs into a map
+func parseAgentAliases(value string) map[string]string {
+	aliases := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		name, aliasID, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && name != "" && aliasID != "" {
+			aliases[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(aliasID)
+		}
+	}
+	return aliases
+}
+
+// ResolveAgentAlias returns the agent alias ID configured for a name in RAGBOT_AGENT_ALIASES
+func (s *BedrockService) ResolveAgentAlias(name string) (string, bool) {
+	aliasID, ok := s.agentAliases[strings.ToLower(name)]
+	return aliasID, ok
+}
+
+// AgentAliasNames returns the names of the configured agent aliases
+func (s *BedrockService) AgentAliasNames() []string {
+	names := []string{}
+	for name := range s.agentAliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // FormatTraceback formats the traceback information in a Slack-friendly way
@@ -92,8 +226,8 @@ func FormatTraceback(traceback interface{}) string {
 }
 
 // InvokeBedrockAgent invokes the Bedrock agent with the provided input
-func (s *BedrockService) InvokeBedrockAgent(inputText, sessionID string, attachments []types.FileAttachment, includeTraceback bool) (interface{}, error) {
-	fmt.Printf("Session Sample ID: %s\n", sessionID)
+func (s *BedrockService) InvokeBedrockAgent(inputText, sessionID string, attachments []types.FileAttachment, includeTraceback bool, sessionContext types.SessionContext) (interface{}, error) {
+	utils.LogInfo(fmt.Sprintf("Invoking Bedrock agent in session %s", sessionID))
 
 	// Check agent health
 	healthStatus, err := s.CheckBedrockAgentHealth()
@@ -114,91 +248,282 @@ func (s *BedrockService) InvokeBedrockAgent(inputText, sessionID string, attachm
 		}, nil
 	}
 
+	// Use the requested alias if one was selected, otherwise the configured default
+	agentAliasID := s.agentAliasID
+	if sessionContext.AgentAliasID != "" {
+		agentAliasID = sessionContext.AgentAliasID
+	}
+
 	// Set up the parameters for the InvokeAgent operation
 	input := &bedrockagentruntime.InvokeAgentInput{
-		AgentAliasId: aws.String(s.agentAliasID),
+		AgentAliasId: aws.String(agentAliasID),
 		AgentId:      aws.String(s.agentID),
 		SessionId:    aws.String(sessionID),
 		InputText:    aws.String(inputText),
 		EnableTrace:  aws.Bool(true),
 	}
 
-	// Note: The API has changed and file attachments are no longer supported in the same way.
-	// If you need file attachments, you'll need to update this code based on the new AWS SDK version.
+	// Pass Slack context (e.g. who is asking, earlier thread messages) to the agent
+	sessionState := &bedrockagentruntime_types.SessionState{}
+	if len(sessionContext.SessionAttributes) > 0 {
+		sessionState.SessionAttributes = sessionContext.SessionAttributes
+	}
+	if len(sessionContext.PromptSessionAttributes) > 0 {
+		sessionState.PromptSessionAttributes = sessionContext.PromptSessionAttributes
+	}
+
+	// Restrict knowledge base retrieval to the documents allowed for this channel
+	if len(sessionContext.KnowledgeBaseFilters) > 0 {
+		if s.knowledgeBaseID == "" {
+			return types.ErrorResponse{
+				Error: "Knowledge base filters are configured but the knowledge base ID is not",
+			}, nil
+		}
+
+		retrievalConfig, err := s.retrievalConfiguration(0, sessionContext.KnowledgeBaseFilters)
+		if err != nil {
+			return types.ErrorResponse{
+				Error:         err.Error(),
+				OriginalError: err,
+			}, nil
+		}
+
+		sessionState.KnowledgeBaseConfigurations = []bedrockagentruntime_types.KnowledgeBaseConfiguration{
+			{
+				KnowledgeBaseId:        aws.String(s.knowledgeBaseID),
+				RetrievalConfiguration: retrievalConfig,
+			},
+		}
+	}
+
+	// Attach files for the agent to use in its answer
 	if len(attachments) > 0 {
-		fmt.Println("Warning: File attachments are not supported in the current SDK version. Ignoring attachments.")
+		files, err := inputFiles(attachments)
+		if err != nil {
+			return types.ErrorResponse{
+				Error:         err.Error(),
+				OriginalError: err,
+			}, nil
+		}
+		sessionState.Files = files
+	}
+
+	// Stream the final answer as it is generated if someone is listening
+	if sessionContext.OnChunk != nil {
+		input.StreamingConfigurations = &bedrockagentruntime_types.StreamingConfigurations{
+			StreamFinalResponse: true,
+		}
+	}
+
+	if sessionState.SessionAttributes != nil || sessionState.PromptSessionAttributes != nil || sessionState.KnowledgeBaseConfigurations != nil || sessionState.Files != nil {
+		input.SessionState = sessionState
+	}
+
+	var responseText string
+	var traceInfo interface{}
+	var guardrail *types.GuardrailIntervention
+	citations := []types.Citation{}
+
+	// Keep invoking the agent while it hands actions back to us to run locally
+	for round := 0; ; round++ {
+		turn, err := s.invokeAgentTurn(input, sessionContext.OnChunk)
+		if err != nil {
+			return types.ErrorResponse{
+				Error:         err.Error(),
+				OriginalError: err,
+			}, nil
+		}
+
+		responseText += turn.text
+		citations = append(citations, turn.citations...)
+		if turn.trace != nil {
+			traceInfo = turn.trace
+		}
+		if guardrail == nil {
+			guardrail = turn.guardrail
+		}
+
+		if turn.returnControl == nil {
+			break
+		}
+		if round >= maxReturnControlRounds {
+			return types.ErrorResponse{
+				Error: fmt.Sprintf("Agent returned control more than %d times, giving up", maxReturnControlRounds),
+			}, nil
+		}
+
+		// Send the action results back in the same session, without new input text
+		results := s.runReturnControlActions(sessionID, turn.returnControl, sessionContext)
+		input = &bedrockagentruntime.InvokeAgentInput{
+			AgentAliasId:            aws.String(agentAliasID),
+			AgentId:                 aws.String(s.agentID),
+			SessionId:               aws.String(sessionID),
+			EnableTrace:             aws.Bool(true),
+			StreamingConfigurations: input.StreamingConfigurations,
+			SessionState: &bedrockagentruntime_types.SessionState{
+				InvocationId:                   turn.returnControl.InvocationId,
+				ReturnControlInvocationResults: results,
+				SessionAttributes:              sessionState.SessionAttributes,
+				PromptSessionAttributes:        sessionState.PromptSessionAttributes,
+				KnowledgeBaseConfigurations:    sessionState.KnowledgeBaseConfigurations,
+			},
+		}
+	}
+
+	// If we didn't get any response text, use a fallback message unless a guardrail blocked it
+	if responseText == "" && guardrail == nil {
+		responseText = fmt.Sprintf("Invoked agent successfully with session ID: %s, but received no response text.", sessionID)
+	}
+
+	// The answer may repeat secrets from the question, so only its size is logged
+	utils.LogInfo(fmt.Sprintf("Bedrock agent answered in session %s (%d characters)", sessionID, len(responseText)))
+
+	response := types.AgentResponse{
+		Response:  responseText,
+		Citations: citations,
+		Guardrail: guardrail,
+	}
+
+	// Include the formatted traceback if requested
+	if includeTraceback {
+		response.Traceback = FormatTraceback(traceInfo)
+	}
+
+	return response, nil
+}
+
+// EndAgentSession tells the agent to end a session on the alias it talks to (the configured
+// alias when aliasID is empty), discarding its conversation history
+func (s *BedrockService) EndAgentSession(sessionID, aliasID string) error {
+	if aliasID == "" {
+		aliasID = s.agentAliasID
+	}
+
+	output, err := s.agentRuntimeClient.InvokeAgent(context.Background(), &bedrockagentruntime.InvokeAgentInput{
+		AgentAliasId: aws.String(aliasID),
+		AgentId:      aws.String(s.agentID),
+		SessionId:    aws.String(sessionID),
+		InputText:    aws.String("End session"),
+		EndSession:   aws.Bool(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to end agent session: %w", err)
+	}
+
+	// Drain the stream so the request completes
+	if stream := output.GetStream(); stream != nil {
+		for range stream.Events() {
+		}
+		if err := stream.Close(); err != nil {
+			utils.LogWarning(fmt.Sprintf("Error closing stream: %v", err))
+		}
+	}
+
+	return nil
+}
+
+// inputFiles converts base64 encoded attachments into files the agent can read in its answer
+func inputFiles(attachments []types.FileAttachment) ([]bedrockagentruntime_types.InputFile, error) {
+	files := []bedrockagentruntime_types.InputFile{}
+	for _, attachment := range attachments {
+		data, err := base64.StdEncoding.DecodeString(attachment.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode attachment %s: %w", attachment.Name, err)
+		}
+		files = append(files, bedrockagentruntime_types.InputFile{
+			Name: aws.String(attachment.Name),
+			Source: &bedrockagentruntime_types.FileSource{
+				SourceType: bedrockagentruntime_types.FileSourceTypeByteContent,
+				ByteContent: &bedrockagentruntime_types.ByteContentFile{
+					Data:      data,
+					MediaType: aws.String(attachment.MediaType),
+				},
+			},
+			UseCase: bedrockagentruntime_types.FileUseCaseChat,
+		})
 	}
+	return files, nil
+}
+
+// agentTurn is the output of a single InvokeAgent call
+type agentTurn struct {
+	text          string
+	trace         interface{}
+	citations     []types.Citation
+	returnControl *bedrockagentruntime_types.ReturnControlPayload
+	guardrail     *types.GuardrailIntervention
+}
+
+// invokeAgentTurn calls InvokeAgent and reads the whole response stream, passing each chunk
+// of the answer to onChunk as it arrives when it is set
+func (s *BedrockService) invokeAgentTurn(input *bedrockagentruntime.InvokeAgentInput, onChunk func(text string)) (agentTurn, error) {
+	turn := agentTurn{}
 
 	// Create and execute the InvokeAgent command
 	output, err := s.agentRuntimeClient.InvokeAgent(context.Background(), input)
 	if err != nil {
-		return types.ErrorResponse{
-			Error:        err.Error(),
-			OriginalError: err,
-		}, nil
+		return turn, err
 	}
 
 	// Get the event stream from the output
 	stream := output.GetStream()
 	if stream == nil {
-		return "No response stream available from the agent", nil
+		turn.text = "No response stream available from the agent"
+		return turn, nil
 	}
 
-	// Read all events from the stream
-	var responseText string
-	var traceInfo interface{}
-
-	// Channel to receive events
-	eventsChan := stream.Events()
-
 	// Process all events from the stream
-	for event := range eventsChan {
+	for event := range stream.Events() {
 		// Type switch to handle different event types
 		switch v := event.(type) {
 		case *bedrockagentruntime_types.ResponseStreamMemberChunk:
 			// This is a chunk of the response text
-			if v.Value.Bytes != nil && len(v.Value.Bytes) > 0 {
-				// Convert bytes to string and append to response text
-				responseText += string(v.Value.Bytes)
+			if len(v.Value.Bytes) > 0 {
+				turn.text += string(v.Value.Bytes)
+				if onChunk != nil {
+					onChunk(string(v.Value.Bytes))
+				}
+			}
+			if v.Value.Attribution != nil {
+				turn.citations = append(turn.citations, convertCitations(v.Value.Attribution.Citations)...)
 			}
 		case *bedrockagentruntime_types.ResponseStreamMemberTrace:
 			// This contains the trace information
-			traceInfo = v.Value
+			turn.trace = v.Value
+
+			// Keep the first guardrail intervention, the answer is blocked from then on
+			if guardrailTrace, ok := v.Value.Trace.(*bedrockagentruntime_types.TraceMemberGuardrailTrace); ok && turn.guardrail == nil {
+				turn.guardrail = agentGuardrailIntervention(guardrailTrace.Value)
+			}
+		case *bedrockagentruntime_types.ResponseStreamMemberReturnControl:
+			// The agent wants us to run an action and send back the result
+			payload := v.Value
+			turn.returnControl = &payload
 		default:
-			// Skip other event types (Files, ReturnControl, etc.)
-			fmt.Printf("Received event of type: %T\n", v)
+			// Skip other event types (Files, etc.)
+			utils.LogDebug(fmt.Sprintf("Skipping agent event of type %T", v))
 		}
 	}
 
 	// Check for any errors during stream processing
 	if err := stream.Err(); err != nil {
-		return types.ErrorResponse{
-			Error:        fmt.Sprintf("Error processing stream: %v", err),
-			OriginalError: err,
-		}, nil
+		return turn, fmt.Errorf("error processing stream: %w", err)
 	}
 
 	// Close the stream
 	if err := stream.Close(); err != nil {
-		fmt.Printf("Warning: Error closing stream: %v\n", err)
-	}
-
-	// If we didn't get any response text, use a fallback message
-	if responseText == "" {
-		responseText = fmt.Sprintf("Invoked agent successfully with session ID: %s, but received no response text.", sessionID)
+		utils.LogWarning(fmt.Sprintf("Error closing stream: %v", err))
 	}
 
-	fmt.Println("AWS Bedrock agent response:", responseText)
+	return turn, nil
+}
 
-	// Return both response and formatted traceback if requested
-	if includeTraceback {
-		return types.AgentResponse{
-			Response:  responseText,
-			Traceback: FormatTraceback(traceInfo),
-		}, nil
+// dataSourceIDOrDefault returns the data source ID, or the configured one when it is empty
+func (s *BedrockService) dataSourceIDOrDefault(dataSourceID string) string {
+	if dataSourceID == "" {
+		return s.dataSourceID
 	}
-
-	return responseText, nil
+	return dataSourceID
 }
 
 // GetKnowledgeBaseStatus gets the status of the knowledge base
@@ -234,6 +559,12 @@ func (s *BedrockService) GetKnowledgeBaseStatus() (interface{}, error) {
 
 // GetAgentStatus gets the status of the agent
 func (s *BedrockService) GetAgentStatus() (interface{}, error) {
+	if s.agentID == "" {
+		return types.ErrorResponse{
+			Error: "Agent ID is not configured",
+		}, nil
+	}
+
 	input := &bedrockagent.GetAgentInput{
 		AgentId: aws.String(s.agentID),
 	}
@@ -263,9 +594,11 @@ func (s *BedrockService) GetAgentStatus() (interface{}, error) {
 	}, nil
 }
 
-// GetDataSource gets information about the data source
-func (s *BedrockService) GetDataSource() (interface{}, error) {
-	if s.knowledgeBaseID == "" || s.dataSourceID == "" {
+// GetDataSource gets information about the latest ingestion job of a data source (the
+// configured one when dataSourceID is empty)
+func (s *BedrockService) GetDataSource(dataSourceID string) (interface{}, error) {
+	dataSourceID = s.dataSourceIDOrDefault(dataSourceID)
+	if s.knowledgeBaseID == "" || dataSourceID == "" {
 		return types.ErrorResponse{
 			Error: "Knowledge base ID or data source ID is not configured",
 		}, nil
@@ -274,9 +607,12 @@ func (s *BedrockService) GetDataSource() (interface{}, error) {
 	// Retrieve the last ingestion job for the data source
 	input := &bedrockagent.ListIngestionJobsInput{
 		KnowledgeBaseId: aws.String(s.knowledgeBaseID),
-		DataSourceId:    aws.String(s.dataSourceID),
+		DataSourceId:    aws.String(dataSourceID),
 		MaxResults:      aws.Int32(1),
-		// SortBy is not available in the current version, so we're not setting it
+		SortBy: &bedrockagent_types.IngestionJobSortBy{
+			Attribute: bedrockagent_types.IngestionJobSortByAttributeStartedAt,
+			Order:     bedrockagent_types.SortOrderDescending,
+		},
 	}
 
 	resp, err := s.agentClient.ListIngestionJobs(context.Background(), input)
@@ -303,7 +639,7 @@ func (s *BedrockService) GetDataSource() (interface{}, error) {
 	return types.DataSourceInfo{
 		DataSourceID:    *job.DataSourceId,
 		KnowledgeBaseID: *job.KnowledgeBaseId,
-		Description:     *job.Description,
+		Description:     aws.ToString(job.Description),
 		Status:          string(job.Status),
 		StartedAt:       *job.StartedAt,
 		UpdatedAt:       *job.UpdatedAt,
@@ -311,9 +647,11 @@ func (s *BedrockService) GetDataSource() (interface{}, error) {
 	}, nil
 }
 
-// SyncDataSource triggers a synchronization of the data source
-func (s *BedrockService) SyncDataSource() (interface{}, error) {
-	if s.knowledgeBaseID == "" || s.dataSourceID == "" {
+// SyncDataSource triggers a synchronization of a data source (the configured one when
+// dataSourceID is empty). The reason, e.g. "Manual", is recorded in the job description.
+func (s *BedrockService) SyncDataSource(dataSourceID, reason string) (interface{}, error) {
+	dataSourceID = s.dataSourceIDOrDefault(dataSourceID)
+	if s.knowledgeBaseID == "" || dataSourceID == "" {
 		return types.ErrorResponse{
 			Error: "Knowledge base ID or data source ID is not configured",
 		}, nil
@@ -321,8 +659,8 @@ func (s *BedrockService) SyncDataSource() (interface{}, error) {
 
 	input := &bedrockagent.StartIngestionJobInput{
 		KnowledgeBaseId: aws.String(s.knowledgeBaseID),
-		DataSourceId:    aws.String(s.dataSourceID),
-		Description:     aws.String("Manual sync triggered on " + time.Now().Format(time.RFC3339)),
+		DataSourceId:    aws.String(dataSourceID),
+		Description:     aws.String(reason + " sync triggered on " + time.Now().Format(time.RFC3339)),
 	}
 
 	resp, err := s.agentClient.StartIngestionJob(context.Background(), input)
@@ -342,9 +680,11 @@ func (s *BedrockService) SyncDataSource() (interface{}, error) {
 	}, nil
 }
 
-// GetDataSourceConfig gets the configuration of the data source
-func (s *BedrockService) GetDataSourceConfig() (interface{}, error) {
-	if s.knowledgeBaseID == "" || s.dataSourceID == "" {
+// GetDataSourceConfig gets the configuration of a data source (the configured one when
+// dataSourceID is empty)
+func (s *BedrockService) GetDataSourceConfig(dataSourceID string) (interface{}, error) {
+	dataSourceID = s.dataSourceIDOrDefault(dataSourceID)
+	if s.knowledgeBaseID == "" || dataSourceID == "" {
 		return types.ErrorResponse{
 			Error: "Knowledge base ID or data source ID is not configured",
 		}, nil
@@ -352,7 +692,7 @@ func (s *BedrockService) GetDataSourceConfig() (interface{}, error) {
 
 	input := &bedrockagent.GetDataSourceInput{
 		KnowledgeBaseId: aws.String(s.knowledgeBaseID),
-		DataSourceId:    aws.String(s.dataSourceID),
+		DataSourceId:    aws.String(dataSourceID),
 	}
 
 	resp, err := s.agentClient.GetDataSource(context.Background(), input)
@@ -410,9 +750,11 @@ func (s *BedrockService) ListDataSources() (interface{}, error) {
 	}, nil
 }
 
-// GetIngestionJobStatus gets the status of an ingestion job
-func (s *BedrockService) GetIngestionJobStatus(jobID string) (interface{}, error) {
-	if s.knowledgeBaseID == "" || s.dataSourceID == "" {
+// GetIngestionJobStatus gets the status of an ingestion job of a data source (the configured
+// one when dataSourceID is empty)
+func (s *BedrockService) GetIngestionJobStatus(dataSourceID, jobID string) (interface{}, error) {
+	dataSourceID = s.dataSourceIDOrDefault(dataSourceID)
+	if s.knowledgeBaseID == "" || dataSourceID == "" {
 		return types.ErrorResponse{
 			Error: "Knowledge base ID or data source ID is not configured",
 		}, nil
@@ -420,7 +762,7 @@ func (s *BedrockService) GetIngestionJobStatus(jobID string) (interface{}, error
 
 	input := &bedrockagent.GetIngestionJobInput{
 		KnowledgeBaseId: aws.String(s.knowledgeBaseID),
-		DataSourceId:    aws.String(s.dataSourceID),
+		DataSourceId:    aws.String(dataSourceID),
 		IngestionJobId:  aws.String(jobID),
 	}
 
@@ -433,29 +775,31 @@ func (s *BedrockService) GetIngestionJobStatus(jobID string) (interface{}, error
 	}
 
 	failureReasons := []string{}
+	failures := []types.IngestionFailure{}
 	for _, reason := range resp.IngestionJob.FailureReasons {
 		failureReasons = append(failureReasons, reason)
+		failures = append(failures, parseFailureReason(reason))
 	}
 
-	statistics := ""
-	if resp.IngestionJob.Statistics != nil {
-		// Format statistics based on what's available in the current SDK version
-		statistics = fmt.Sprintf("Status: %s", resp.IngestionJob.Status)
-	}
+	stats := convertIngestionStatistics(resp.IngestionJob.Statistics)
 
 	return types.IngestionJobStatus{
 		IngestionJobID:  *resp.IngestionJob.IngestionJobId,
+		DataSourceID:    aws.ToString(resp.IngestionJob.DataSourceId),
 		Status:          string(resp.IngestionJob.Status),
 		StartedAt:       *resp.IngestionJob.StartedAt,
 		UpdatedAt:       *resp.IngestionJob.UpdatedAt,
-		Statistics:      statistics,
+		Statistics:      FormatIngestionStatistics(stats),
 		FailureReasons:  failureReasons,
+		Stats:           stats,
+		Failures:        failures,
 		RawResponse:     resp.IngestionJob,
 	}, nil
 }
 
 // CheckBedrockAgentHealth checks the health of the Bedrock agent
 func (s *BedrockService) CheckBedrockAgentHealth() (types.HealthStatus, error) {
+	var err error
 	issues := []types.HealthIssue{}
 	details := types.HealthDetails{
 		Region:       s.region,
@@ -463,10 +807,13 @@ func (s *BedrockService) CheckBedrockAgentHealth() (types.HealthStatus, error) {
 		AgentAliasID: s.agentAliasID,
 	}
 
-	// Check agent status
-	agentStatusResp, err := s.GetAgentStatus()
-	if err != nil {
-		return types.HealthStatus{}, err
+	// Check agent status, unless answers come from RetrieveAndGenerate without an agent
+	agentStatusResp := interface{}(nil)
+	if s.agentID != "" {
+		agentStatusResp, err = s.GetAgentStatus()
+		if err != nil {
+			return types.HealthStatus{}, err
+		}
 	}
 
 	// Check if response contains an error
@@ -523,8 +870,11 @@ func (s *BedrockService) CheckBedrockAgentHealth() (types.HealthStatus, error) {
 	}, nil
 }
 
-// MonitorIngestionJob monitors an ingestion job
-func (s *BedrockService) MonitorIngestionJob(jobID string, maxWaitMinutes int) (interface{}, error) {
+// MonitorIngestionJob monitors an ingestion job of a data source (the configured one when
+// dataSourceID is empty) until it finishes
+func (s *BedrockService) MonitorIngestionJob(dataSourceID, jobID string, maxWaitMinutes int) (interface{}, error) {
+	dataSourceID = s.dataSourceIDOrDefault(dataSourceID)
+
 	// Store initial KB timestamp
 	kbStatusResp, err := s.GetKnowledgeBaseStatus()
 	if err != nil {
@@ -552,7 +902,7 @@ func (s *BedrockService) MonitorIngestionJob(jobID string, maxWaitMinutes int) (
 
 	// Poll every 30 seconds
 	for time.Now().Before(timeout) && !jobComplete {
-		jobStatusResp, err := s.GetIngestionJobStatus(jobID)
+		jobStatusResp, err := s.GetIngestionJobStatus(dataSourceID, jobID)
 		if err != nil {
 			return types.ErrorResponse{
 				Error:        fmt.Sprintf("Failed to get job status: %v", err),
@@ -622,6 +972,9 @@ func (s *BedrockService) MonitorIngestionJob(jobID string, maxWaitMinutes int) (
 	if agentStatusVal, ok := agentStatusResp.(types.AgentStatus); ok {
 		agentStatus = agentStatusVal
 		agentReady = agentStatusVal.AgentStatus == "READY" || agentStatusVal.AgentStatus == "PREPARED"
+	} else if s.agentID == "" {
+		// Without an agent there is nothing to wait for
+		agentReady = true
 	} else {
 		return types.ErrorResponse{
 			Error: "Failed to get agent status",
@@ -644,7 +997,7 @@ func (s *BedrockService) MonitorIngestionJob(jobID string, maxWaitMinutes int) (
 	return types.MonitorIngestionJobStatus{
 		Success:            jobStatusString == "COMPLETE" && kbUpdated && agentReady,
 		KnowledgeBaseID:    kbID,
-		DataSourceID:       s.dataSourceID,
+		DataSourceID:       dataSourceID,
 		IngestionJobID:     jobID,
 		JobStatus:          jobStatusString,
 		InitialKBTimestamp: initialKBTimestamp,
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"slack-rag-server/src/types"
+)
+
+// listDataSources returns the data sources of the knowledge base, ordered by name
+func (s *BedrockService) listDataSources() ([]types.DataSource, error) {
+	response, err := s.ListDataSources()
+	if err != nil {
+		return nil, err
+	}
+	if errorResp, ok := response.(types.ErrorResponse); ok {
+		return nil, fmt.Errorf("%s", errorResp.Error)
+	}
+
+	list, ok := response.(types.DataSourceList)
+	if !ok {
+		return nil, fmt.Errorf("unexpected response: %v", response)
+	}
+
+	sort.Slice(list.DataSources, func(i, j int) bool {
+		return strings.ToLower(list.DataSources[i].Name) < strings.ToLower(list.DataSources[j].Name)
+	})
+	return list.DataSources, nil
+}
+
+// ResolveDataSource returns the ID of the data source with the given name or ID. An empty
+// value means the configured data source. The error of an unknown data source suggests
+// close matches and lists the available names so they can be copied into the command.
+func (s *BedrockService) ResolveDataSource(nameOrID string) (string, error) {
+	nameOrID = strings.TrimSpace(nameOrID)
+	if nameOrID == "" && s.dataSourceID != "" {
+		return s.dataSourceID, nil
+	}
+
+	dataSources, err := s.listDataSources()
+	if err != nil {
+		return "", fmt.Errorf("could not list data sources: %w", err)
+	}
+
+	if nameOrID == "" {
+		return "", fmt.Errorf("no default data source is configured, pass one of: %s", formatDataSourceNames(dataSources))
+	}
+
+	for _, source := range dataSources {
+		if source.DataSourceID == nameOrID || strings.EqualFold(source.Name, nameOrID) {
+			return source.DataSourceID, nil
+		}
+	}
+
+	var suggestions []types.DataSource
+	lower := strings.ToLower(nameOrID)
+	for _, source := range dataSources {
+		name := strings.ToLower(source.Name)
+		if strings.Contains(name, lower) || strings.Contains(lower, name) ||
+			strings.HasPrefix(strings.ToLower(source.DataSourceID), lower) {
+			suggestions = append(suggestions, source)
+		}
+	}
+
+	message := fmt.Sprintf("unknown data source %q", nameOrID)
+	if len(suggestions) > 0 {
+		message += fmt.Sprintf(". Did you mean %s?", formatDataSourceNames(suggestions))
+	}
+	if len(dataSources) == 0 {
+		return "", fmt.Errorf("%s. The knowledge base has no data sources", message)
+	}
+	return "", fmt.Errorf("%s. Available data sources: %s", message, formatDataSourceNames(dataSources))
+}
+
+// formatDataSourceNames lists data sources as `name` (ID)
+func formatDataSourceNames(dataSources []types.DataSource) string {
+	var names []string
+	for _, source := range dataSources {
+		names = append(names, fmt.Sprintf("`%s` (%s)", source.Name, source.DataSourceID))
+	}
+	return strings.Join(names, ", ")
+}
+
+// SyncAllDataSources starts an ingestion job on every data source of the knowledge base.
+// Data sources that fail to start are reported in Failures rather than stopping the others.
+func (s *BedrockService) SyncAllDataSources(reason string) (interface{}, error) {
+	if s.knowledgeBaseID == "" {
+		return types.ErrorResponse{
+			Error: "Knowledge base ID is not configured",
+		}, nil
+	}
+
+	dataSources, err := s.listDataSources()
+	if err != nil {
+		return types.ErrorResponse{
+			Error:         err.Error(),
+			OriginalError: err,
+		}, nil
+	}
+
+	batch := types.DataSourceSyncBatch{Jobs: []types.DataSourceSync{}}
+	for _, source := range dataSources {
+		response, err := s.SyncDataSource(source.DataSourceID, reason)
+		if err == nil {
+			if errorResp, ok := response.(types.ErrorResponse); ok {
+				err = fmt.Errorf("%s", errorResp.Error)
+			}
+		}
+
+		dsSync, ok := response.(types.DataSourceSync)
+		if err == nil && !ok {
+			err = fmt.Errorf("unexpected response: %v", response)
+		}
+		if err != nil {
+			batch.Failures = append(batch.Failures, types.DataSourceSyncFailure{
+				DataSourceID: source.DataSourceID,
+				Name:         source.Name,
+				Error:        err.Error(),
+			})
+			continue
+		}
+
+		dsSync.Name = source.Name
+		batch.Jobs = append(batch.Jobs, dsSync)
+	}
+
+	return batch, nil
+}
+
+// MonitorIngestionJobs monitors several ingestion jobs at once and returns their outcomes in
+// the order of jobs, once every job has finished or timed out
+func (s *BedrockService) MonitorIngestionJobs(jobs []types.DataSourceSync, maxWaitMinutes int) []types.MonitorIngestionJobStatus {
+	results := make([]types.MonitorIngestionJobStatus, len(jobs))
+
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		wg.Add(1)
+		go func(i int, job types.DataSourceSync) {
+			defer wg.Done()
+
+			result := types.MonitorIngestionJobStatus{
+				IngestionJobID: job.IngestionJobID,
+				DataSourceID:   job.DataSourceID,
+			}
+
+			response, err := s.MonitorIngestionJob(job.DataSourceID, job.IngestionJobID, maxWaitMinutes)
+			switch resp := response.(type) {
+			case types.MonitorIngestionJobStatus:
+				result = resp
+			case types.ErrorResponse:
+				result.Message = resp.Error
+			default:
+				if err != nil {
+					result.Message = err.Error()
+				} else {
+					result.Message = fmt.Sprintf("unexpected response: %v", response)
+				}
+			}
+			results[i] = result
+		}(i, job)
+	}
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// directoryCacheTTL is how long Slack user lookups are cached
+const directoryCacheTTL = time.Hour
+
+// cachedUser is a Slack user along with the time it was fetched
+type cachedUser struct {
+	user      *slack.User
+	fetchedAt time.Time
+}
+
+// cachedChannel is a Slack channel along with the time it was fetched
+type cachedChannel struct {
+	channel   *slack.Channel
+	fetchedAt time.Time
+}
+
+// cachedMembers is the member list of a user group along with the time it was fetched
+type cachedMembers struct {
+	members   map[string]bool
+	fetchedAt time.Time
+}
+
+// SlackDirectory resolves Slack users, channels and user groups, caching the results to avoid rate limits
+type SlackDirectory struct {
+	api        *slack.Client
+	mu         sync.Mutex
+	users      map[string]cachedUser
+	channels   map[string]cachedChannel
+	userGroups map[string]cachedMembers
+}
+
+// NewSlackDirectory creates a new SlackDirectory
+func NewSlackDirectory(api *slack.Client) *SlackDirectory {
+	return &SlackDirectory{
+		api:        api,
+		users:      make(map[string]cachedUser),
+		channels:   make(map[string]cachedChannel),
+		userGroups: make(map[string]cachedMembers),
+	}
+}
+
+// GetUser returns a Slack user, using the cache when possible
+func (d *SlackDirectory) GetUser(userID string) (*slack.User, error) {
+	d.mu.Lock()
+	cached, ok := d.users[userID]
+	d.mu.Unlock()
+
+	if ok && time.Since(cached.fetchedAt) < directoryCacheTTL {
+		return cached.user, nil
+	}
+
+	user, err := d.api.GetUserInfo(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	d.users[userID] = cachedUser{user: user, fetchedAt: time.Now()}
+	d.mu.Unlock()
+
+	return user, nil
+}
+
+// GetUserName returns a user's display name, falling back to the real name
+// and then the user ID if the user cannot be resolved
+func (d *SlackDirectory) GetUserName(userID string) string {
+	user, err := d.GetUser(userID)
+	if err != nil {
+		return userID
+	}
+
+	if user.Profile.DisplayName != "" {
+		return user.Profile.DisplayName
+	}
+	if user.RealName != "" {
+		return user.RealName
+	}
+	return user.Name
+}
+
+// GetChannel returns a Slack channel, using the cache when possible
+func (d *SlackDirectory) GetChannel(channelID string) (*slack.Channel, error) {
+	d.mu.Lock()
+	cached, ok := d.channels[channelID]
+	d.mu.Unlock()
+
+	if ok && time.Since(cached.fetchedAt) < directoryCacheTTL {
+		return cached.channel, nil
+	}
+
+	channel, err := d.api.GetConversationInfo(&slack.GetConversationInfoInput{
+		ChannelID: channelID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	d.channels[channelID] = cachedChannel{channel: channel, fetchedAt: time.Now()}
+	d.mu.Unlock()
+
+	return channel, nil
+}
+
+// IsUserGroupMember checks whether a user belongs to a Slack user group, using the cache when possible
+func (d *SlackDirectory) IsUserGroupMember(userGroupID, userID string) (bool, error) {
+	d.mu.Lock()
+	cached, ok := d.userGroups[userGroupID]
+	d.mu.Unlock()
+
+	if ok && time.Since(cached.fetchedAt) < directoryCacheTTL {
+		return cached.members[userID], nil
+	}
+
+	memberIDs, err := d.api.GetUserGroupMembers(userGroupID)
+	if err != nil {
+		return false, err
+	}
+
+	members := make(map[string]bool, len(memberIDs))
+	for _, memberID := range memberIDs {
+		members[memberID] = true
+	}
+
+	d.mu.Lock()
+	d.userGroups[userGroupID] = cachedMembers{members: members, fetchedAt: time.Now()}
+	d.mu.Unlock()
+
+	return members[userID], nil
+}
+
+// Session attributes naming the Slack user who asked and the channel they asked in
+const (
+	UserIDAttribute    = "slackUserId"
+	ChannelIDAttribute = "slackChannelId"
+)
+
+// IdentityAttributes describes the asking user and the channel a question was asked in as
+// agent session attributes. Anything that cannot be resolved is left out.
+func (d *SlackDirectory) IdentityAttributes(userID, channelID string) map[string]string {
+	attributes := map[string]string{
+		UserIDAttribute:    userID,
+		ChannelIDAttribute: channelID,
+	}
+
+	if user, err := d.GetUser(userID); err == nil {
+		attributes["slackUserName"] = d.GetUserName(userID)
+		setIfPresent(attributes, "slackUserRealName", user.RealName)
+		setIfPresent(attributes, "slackUserEmail", user.Profile.Email)
+		setIfPresent(attributes, "slackUserTimezone", user.TZ)
+		setIfPresent(attributes, "slackUserTitle", user.Profile.Title)
+	}
+
+	if channel, err := d.GetChannel(channelID); err == nil {
+		setIfPresent(attributes, "slackChannelName", channel.Name)
+		attributes["slackChannelIsPrivate"] = strconv.FormatBool(channel.IsPrivate || channel.IsIM || channel.IsMpIM)
+		attributes["slackChannelIsShared"] = strconv.FormatBool(channel.IsShared || channel.IsExtShared || channel.IsPendingExtShared)
+	}
+
+	return attributes
+}
+
+// setIfPresent sets an attribute when the value is not empty
+func setIfPresent(attributes map[string]string, key, value string) {
+	if value != "" {
+		attributes[key] = value
+	}
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"slack-rag-server/src/types"
+	"slack-rag-server/src/utils"
+)
+
+// Roles of the agent aliases in an experiment report
+const (
+	AliasRoleControl   = "control"
+	AliasRoleCandidate = "candidate"
+	AliasRoleOther     = "other"
+)
+
+// AliasExperiment splits threads between the default agent alias and a candidate alias and
+// records the latency, errors and feedback of every answer by alias. A thread is assigned
+// by hashing its timestamp, so every follow-up in a thread is answered by the same alias.
+// The candidate is set with RAGBOT_CANDIDATE_ALIAS (an alias ID or a name from
+// RAGBOT_AGENT_ALIASES) and its share of threads with RAGBOT_CANDIDATE_PERCENT. Outcomes are
+// appended to the JSON lines file named in RAGBOT_EXPERIMENT_LOG and reloaded on startup.
+type AliasExperiment struct {
+	mu               sync.Mutex
+	controlAliasID   string
+	candidateAliasID string
+	percent          int
+	since            time.Time
+	outcomes         []types.AnswerOutcome
+	feedback         map[string]types.AnswerFeedback
+	file             *os.File
+}
+
+// experimentRecord is a line of the experiment log, holding either an answer or feedback
+type experimentRecord struct {
+	Answer   *types.AnswerOutcome  `json:"answer,omitempty"`
+	Feedback *types.AnswerFeedback `json:"feedback,omitempty"`
+}
+
+// NewAliasExperiment creates a new AliasExperiment, loading earlier outcomes from RAGBOT_EXPERIMENT_LOG
+func NewAliasExperiment(bedrockService *BedrockService) (*AliasExperiment, error) {
+	experiment := &AliasExperiment{
+		controlAliasID: bedrockService.agentAliasID,
+		since:          time.Now(),
+		feedback:       make(map[string]types.AnswerFeedback),
+	}
+
+	if candidate := os.Getenv("RAGBOT_CANDIDATE_ALIAS"); candidate != "" {
+		if aliasID, ok := bedrockService.ResolveAgentAlias(candidate); ok {
+			candidate = aliasID
+		}
+		experiment.candidateAliasID = candidate
+	}
+
+	if value := os.Getenv("RAGBOT_CANDIDATE_PERCENT"); value != "" {
+		percent, err := strconv.Atoi(value)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("RAGBOT_CANDIDATE_PERCENT must be a number from 0 to 100, got %q", value)
+		}
+		experiment.percent = percent
+	}
+
+	if experiment.Enabled() && bedrockService.BackendName() != BackendAgent {
+		utils.LogWarning("RAGBOT_CANDIDATE_ALIAS is ignored because the answering backend is not an agent")
+		experiment.candidateAliasID = ""
+	}
+	if experiment.Enabled() {
+		utils.LogInfo(fmt.Sprintf("Routing %d%% of threads to candidate agent alias %s", experiment.percent, experiment.candidateAliasID))
+	}
+
+	path := os.Getenv("RAGBOT_EXPERIMENT_LOG")
+	if path == "" {
+		return experiment, nil
+	}
+
+	if err := experiment.load(path); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open experiment log: %w", err)
+	}
+	experiment.file = file
+
+	return experiment, nil
+}
+
+// load replays the answers and feedback saved in the experiment log
+func (e *AliasExperiment) load(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read experiment log: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record experimentRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			utils.LogWarning("Skipping invalid experiment log line: " + scanner.Text())
+			continue
+		}
+		if record.Answer != nil {
+			e.outcomes = append(e.outcomes, *record.Answer)
+			if record.Answer.AnsweredAt.Before(e.since) {
+				e.since = record.Answer.AnsweredAt
+			}
+		}
+		if record.Feedback != nil {
+			e.feedback[feedbackKey(record.Feedback.AnswerID, record.Feedback.User)] = *record.Feedback
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read experiment log: %w", err)
+	}
+
+	return nil
+}
+
+// Enabled reports whether a share of threads is routed to a candidate alias
+func (e *AliasExperiment) Enabled() bool {
+	return e.candidateAliasID != "" && e.percent > 0
+}
+
+// AliasFor returns the alias that answers questions in a thread, or "" for the default alias
+func (e *AliasExperiment) AliasFor(thread string) string {
+	if !e.Enabled() {
+		return ""
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(thread))
+	if int(hash.Sum32()%100) < e.percent {
+		return e.candidateAliasID
+	}
+	return ""
+}
+
+// AnsweringAlias returns the ID of the alias that answers a question given the alias chosen
+// for it, where "" means the default alias
+func (e *AliasExperiment) AnsweringAlias(aliasID string) string {
+	if aliasID == "" {
+		return e.controlAliasID
+	}
+	return aliasID
+}
+
+// RecordAnswer records the outcome of an answer
+func (e *AliasExperiment) RecordAnswer(outcome types.AnswerOutcome) {
+	if outcome.AnsweredAt.IsZero() {
+		outcome.AnsweredAt = time.Now()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.outcomes = append(e.outcomes, outcome)
+	e.append(experimentRecord{Answer: &outcome})
+}
+
+// RecordFeedback records a user's vote on an answer, replacing any earlier vote of theirs
+func (e *AliasExperiment) RecordFeedback(feedback types.AnswerFeedback) {
+	if feedback.Timestamp.IsZero() {
+		feedback.Timestamp = time.Now()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.feedback[feedbackKey(feedback.AnswerID, feedback.User)] = feedback
+	e.append(experimentRecord{Feedback: &feedback})
+}
+
+// append writes a record to the experiment log. The caller must hold the lock.
+func (e *AliasExperiment) append(record experimentRecord) {
+	if e.file == nil {
+		return
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		utils.LogError(err, "Error encoding experiment record")
+		return
+	}
+	if _, err := e.file.Write(append(line, '\n')); err != nil {
+		utils.LogError(err, "Error writing experiment log")
+	}
+}
+
+// feedbackKey identifies a user's vote on an answer
+func feedbackKey(answerID, user string) string {
+	return answerID + "|" + user
+}
+
+// Report compares the latency, error rate and feedback of each alias, control and candidate first
+func (e *AliasExperiment) Report() types.ExperimentReport {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	latencies := map[string][]int64{}
+	reports := map[string]*types.AliasReport{}
+	report := func(aliasID string) *types.AliasReport {
+		if reports[aliasID] == nil {
+			reports[aliasID] = &types.AliasReport{AliasID: aliasID, Role: e.role(aliasID)}
+		}
+		return reports[aliasID]
+	}
+
+	for _, outcome := range e.outcomes {
+		alias := report(outcome.AliasID)
+
+		// Questions stopped by a guardrail say nothing about the alias's answers
+		if outcome.Blocked {
+			alias.Blocked++
+			continue
+		}
+
+		alias.Answers++
+		if !outcome.Success {
+			alias.Errors++
+			continue
+		}
+		latencies[outcome.AliasID] = append(latencies[outcome.AliasID], outcome.LatencyMs)
+	}
+
+	for _, feedback := range e.feedback {
+		alias := report(feedback.AliasID)
+		if feedback.Score > 0 {
+			alias.ThumbsUp++
+		} else if feedback.Score < 0 {
+			alias.ThumbsDown++
+		}
+	}
+
+	aliases := []types.AliasReport{}
+	for aliasID, alias := range reports {
+		if alias.Answers > 0 {
+			alias.ErrorRate = float64(alias.Errors) / float64(alias.Answers)
+		}
+		if votes := alias.ThumbsUp + alias.ThumbsDown; votes > 0 {
+			alias.FeedbackScore = float64(alias.ThumbsUp) / float64(votes)
+		}
+		if values := latencies[aliasID]; len(values) > 0 {
+			sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+			var total int64
+			for _, value := range values {
+				total += value
+			}
+			alias.AvgLatencyMs = total / int64(len(values))
+			alias.P50LatencyMs = percentile(values, 50)
+			alias.P95LatencyMs = percentile(values, 95)
+		}
+		aliases = append(aliases, *alias)
+	}
+
+	sort.Slice(aliases, func(i, j int) bool {
+		if roleOrder(aliases[i].Role) != roleOrder(aliases[j].Role) {
+			return roleOrder(aliases[i].Role) < roleOrder(aliases[j].Role)
+		}
+		return aliases[i].AliasID < aliases[j].AliasID
+	})
+
+	return types.ExperimentReport{
+		ControlAliasID:   e.controlAliasID,
+		CandidateAliasID: e.candidateAliasID,
+		CandidatePercent: e.percent,
+		Since:            e.since,
+		Aliases:          aliases,
+	}
+}
+
+// role returns the part an alias plays in the experiment
+func (e *AliasExperiment) role(aliasID string) string {
+	switch {
+	case aliasID == e.controlAliasID:
+		return AliasRoleControl
+	case aliasID == e.candidateAliasID && e.candidateAliasID != "":
+		return AliasRoleCandidate
+	default:
+		return AliasRoleOther
+	}
+}
+
+// roleOrder orders the aliases of a report by role
+func roleOrder(role string) int {
+	switch role {
+	case AliasRoleControl:
+		return 0
+	case AliasRoleCandidate:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []int64, p int) int64 {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
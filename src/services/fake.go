@@ -0,0 +1,94 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"slack-rag-server/src/types"
+)
+
+// fakeAnswer is a canned answer returned by the fake backend for questions containing Match
+type fakeAnswer struct {
+	Match     string                       `json:"match"`
+	Response  string                       `json:"response"`
+	Citations []types.Citation             `json:"citations,omitempty"`
+	Error     string                       `json:"error,omitempty"`
+	DelayMs   int                          `json:"delayMs,omitempty"`
+	Guardrail *types.GuardrailIntervention `json:"guardrail,omitempty"`
+}
+
+// fakeBackend answers questions from canned answers without calling AWS, for local
+// development, replaying recorded traffic and evaluating the bot offline. Answers are read
+// from the JSON file named in RAGBOT_FAKE_ANSWERS_FILE; the first answer whose match appears
+// in the question (ignoring case) is used, and other questions are echoed back.
+type fakeBackend struct {
+	answers []fakeAnswer
+}
+
+// newFakeBackend creates a fake backend from the environment
+func newFakeBackend() (*fakeBackend, error) {
+	backend := &fakeBackend{}
+
+	path := os.Getenv("RAGBOT_FAKE_ANSWERS_FILE")
+	if path == "" {
+		return backend, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fake answers file: %w", err)
+	}
+	if err := json.Unmarshal(content, &backend.answers); err != nil {
+		return nil, fmt.Errorf("failed to parse fake answers file: %w", err)
+	}
+
+	return backend, nil
+}
+
+// Name returns the backend name
+func (b *fakeBackend) Name() string {
+	return BackendFake
+}
+
+// Answer returns the canned answer matching the question
+func (b *fakeBackend) Answer(inputText, sessionID string, attachments []types.FileAttachment, includeTraceback bool, sessionContext types.SessionContext) (interface{}, error) {
+	answer := fakeAnswer{Response: "Fake answer to: " + inputText}
+	for _, candidate := range b.answers {
+		if strings.Contains(strings.ToLower(inputText), strings.ToLower(candidate.Match)) {
+			answer = candidate
+			break
+		}
+	}
+
+	if answer.DelayMs > 0 {
+		time.Sleep(time.Duration(answer.DelayMs) * time.Millisecond)
+	}
+	if answer.Error != "" {
+		return types.ErrorResponse{
+			Error: answer.Error,
+		}, nil
+	}
+
+	if sessionContext.OnChunk != nil {
+		sessionContext.OnChunk(answer.Response)
+	}
+
+	response := types.AgentResponse{
+		Response:  answer.Response,
+		Citations: answer.Citations,
+		Guardrail: answer.Guardrail,
+	}
+	if includeTraceback {
+		response.Traceback = fmt.Sprintf("```\nBackend: %s\nSession: %s\nMatch: %q\n```", BackendFake, sessionID, answer.Match)
+	}
+
+	return response, nil
+}
+
+// EndSession does nothing, the fake backend keeps no sessions
+func (b *fakeBackend) EndSession(sessionID, aliasID string) error {
+	return nil
+}
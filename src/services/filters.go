@@ -0,0 +1,113 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"slack-rag-server/src/types"
+	"slack-rag-server/src/utils"
+)
+
+// userGroupFilters maps a Slack user group to the filters applied to its members
+type userGroupFilters struct {
+	ID      string                 `json:"id"`
+	Filters []types.MetadataFilter `json:"filters"`
+}
+
+// filterPolicyConfig is the JSON layout of RAGBOT_KB_FILTERS_FILE
+type filterPolicyConfig struct {
+	Channels       map[string][]types.MetadataFilter `json:"channels"`
+	UserGroups     []userGroupFilters                `json:"userGroups"`
+	SharedChannels []types.MetadataFilter            `json:"sharedChannels"`
+	Default        []types.MetadataFilter            `json:"default"`
+}
+
+// FilterPolicy decides which knowledge base metadata filters apply to a question
+// based on the channel it was asked in and the user groups of the asker
+type FilterPolicy struct {
+	directory *SlackDirectory
+	config    filterPolicyConfig
+}
+
+// NewFilterPolicy creates a FilterPolicy from the JSON file named in RAGBOT_KB_FILTERS_FILE.
+// Without the variable no filters are applied.
+func NewFilterPolicy(directory *SlackDirectory) (*FilterPolicy, error) {
+	policy := &FilterPolicy{directory: directory}
+
+	path := os.Getenv("RAGBOT_KB_FILTERS_FILE")
+	if path == "" {
+		return policy, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read knowledge base filters file: %w", err)
+	}
+
+	if err := json.Unmarshal(content, &policy.config); err != nil {
+		return nil, fmt.Errorf("failed to parse knowledge base filters file: %w", err)
+	}
+
+	// Validate every filter up front so mistakes surface at startup
+	all := append([]types.MetadataFilter{}, policy.config.SharedChannels...)
+	all = append(all, policy.config.Default...)
+	for _, filters := range policy.config.Channels {
+		all = append(all, filters...)
+	}
+	for _, group := range policy.config.UserGroups {
+		all = append(all, group.Filters...)
+	}
+	if _, err := BuildRetrievalFilter(all); err != nil {
+		return nil, fmt.Errorf("invalid knowledge base filter: %w", err)
+	}
+
+	return policy, nil
+}
+
+// Resolve returns the filters for a question. Channel filters take precedence, then the
+// first matching user group, then the default. Filters for externally shared channels
+// are always added on top so shared channels never see internal documents.
+func (p *FilterPolicy) Resolve(channelID, userID string) []types.MetadataFilter {
+	var filters []types.MetadataFilter
+
+	if channelFilters, ok := p.config.Channels[channelID]; ok {
+		filters = append(filters, channelFilters...)
+	} else if groupFilters, ok := p.userGroupFilters(userID); ok {
+		filters = append(filters, groupFilters...)
+	} else {
+		filters = append(filters, p.config.Default...)
+	}
+
+	if len(p.config.SharedChannels) > 0 && p.isSharedChannel(channelID) {
+		filters = append(filters, p.config.SharedChannels...)
+	}
+
+	return filters
+}
+
+// userGroupFilters returns the filters of the first configured user group the user belongs to
+func (p *FilterPolicy) userGroupFilters(userID string) ([]types.MetadataFilter, bool) {
+	for _, group := range p.config.UserGroups {
+		member, err := p.directory.IsUserGroupMember(group.ID, userID)
+		if err != nil {
+			utils.LogError(err, "Error checking user group membership for "+group.ID)
+			continue
+		}
+		if member {
+			return group.Filters, true
+		}
+	}
+	return nil, false
+}
+
+// isSharedChannel checks whether a channel is shared with other organizations. If the
+// channel cannot be looked up it is treated as shared so restricted documents stay hidden.
+func (p *FilterPolicy) isSharedChannel(channelID string) bool {
+	channel, err := p.directory.GetChannel(channelID)
+	if err != nil {
+		utils.LogError(err, "Error looking up channel "+channelID+", applying shared channel filters")
+		return true
+	}
+	return channel.IsExtShared || channel.IsPendingExtShared
+}
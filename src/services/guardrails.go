@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	bedrockagentruntime_types "github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime/types"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	bedrockruntime_types "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+
+	"slack-rag-server/src/types"
+	"slack-rag-server/src/utils"
+)
+
+// Where a guardrail intervention was reported
+const (
+	GuardrailSourceAgent = "agent"
+	GuardrailSourceApply = "apply_guardrail"
+)
+
+// What a guardrail checked when it intervened
+const (
+	GuardrailStageInput  = "input"
+	GuardrailStageOutput = "output"
+)
+
+// guardrailBlocked is the action of a guardrail policy that blocked content, as opposed to
+// masking it or only detecting it
+const guardrailBlocked = "BLOCKED"
+
+// guardrailChecker checks questions and answers with a standalone Bedrock guardrail via
+// ApplyGuardrail, whichever backend answers them
+type guardrailChecker struct {
+	client  *bedrockruntime.Client
+	id      string
+	version string
+	input   bool
+	output  bool
+}
+
+// newGuardrailChecker creates a guardrail checker from RAGBOT_GUARDRAIL_ID,
+// RAGBOT_GUARDRAIL_VERSION (default DRAFT) and RAGBOT_GUARDRAIL_CHECKS (a comma-separated
+// list of input and output, default both). Returns nil when no guardrail is configured.
+func newGuardrailChecker(cfg aws.Config) (*guardrailChecker, error) {
+	id := os.Getenv("RAGBOT_GUARDRAIL_ID")
+	if id == "" {
+		return nil, nil
+	}
+
+	checker := &guardrailChecker{
+		client:  bedrockruntime.NewFromConfig(cfg),
+		id:      id,
+		version: os.Getenv("RAGBOT_GUARDRAIL_VERSION"),
+		input:   true,
+		output:  true,
+	}
+	if checker.version == "" {
+		checker.version = "DRAFT"
+	}
+
+	if checks := os.Getenv("RAGBOT_GUARDRAIL_CHECKS"); checks != "" {
+		checker.input, checker.output = false, false
+		for _, check := range strings.Split(checks, ",") {
+			switch strings.TrimSpace(check) {
+			case GuardrailStageInput:
+				checker.input = true
+			case GuardrailStageOutput:
+				checker.output = true
+			default:
+				return nil, fmt.Errorf("invalid RAGBOT_GUARDRAIL_CHECKS entry %q, expected %s or %s", check, GuardrailStageInput, GuardrailStageOutput)
+			}
+		}
+	}
+
+	return checker, nil
+}
+
+// checksOutput reports whether answers are checked with the guardrail
+func (c *guardrailChecker) checksOutput() bool {
+	return c != nil && c.output
+}
+
+// check applies the guardrail to a question (input) or answer (output), returning the
+// intervention when the guardrail blocked it
+func (c *guardrailChecker) check(text, stage string) (*types.GuardrailIntervention, error) {
+	if c == nil || strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+
+	source := bedrockruntime_types.GuardrailContentSourceInput
+	switch stage {
+	case GuardrailStageInput:
+		if !c.input {
+			return nil, nil
+		}
+	case GuardrailStageOutput:
+		if !c.output {
+			return nil, nil
+		}
+		source = bedrockruntime_types.GuardrailContentSourceOutput
+	}
+
+	output, err := c.client.ApplyGuardrail(context.Background(), &bedrockruntime.ApplyGuardrailInput{
+		GuardrailIdentifier: aws.String(c.id),
+		GuardrailVersion:    aws.String(c.version),
+		Source:              source,
+		Content: []bedrockruntime_types.GuardrailContentBlock{
+			&bedrockruntime_types.GuardrailContentBlockMemberText{
+				Value: bedrockruntime_types.GuardrailTextBlock{Text: aws.String(text)},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply guardrail %s: %w", c.id, err)
+	}
+	if output.Action != bedrockruntime_types.GuardrailActionGuardrailIntervened {
+		return nil, nil
+	}
+
+	// Guardrails that only masked sensitive information let the text through
+	policies := blockedGuardrailPolicies(output.Assessments)
+	if len(policies) == 0 {
+		return nil, nil
+	}
+
+	return &types.GuardrailIntervention{
+		Source:   GuardrailSourceApply,
+		Stage:    stage,
+		Policies: policies,
+	}, nil
+}
+
+// agentGuardrailIntervention converts a guardrail trace event from the agent into an
+// intervention, returning nil when the guardrail did not block anything
+func agentGuardrailIntervention(trace bedrockagentruntime_types.GuardrailTrace) *types.GuardrailIntervention {
+	if trace.Action != bedrockagentruntime_types.GuardrailActionIntervened {
+		return nil
+	}
+
+	if policies := blockedGuardrailPolicies(trace.InputAssessments); len(policies) > 0 {
+		return &types.GuardrailIntervention{Source: GuardrailSourceAgent, Stage: GuardrailStageInput, Policies: policies}
+	}
+	if policies := blockedGuardrailPolicies(trace.OutputAssessments); len(policies) > 0 {
+		return &types.GuardrailIntervention{Source: GuardrailSourceAgent, Stage: GuardrailStageOutput, Policies: policies}
+	}
+	return nil
+}
+
+// guardrailCheck is one check a guardrail policy made: a topic, filter, word, entity or regex
+// along with the action the guardrail took
+type guardrailCheck struct {
+	Name   string
+	Type   string
+	Action string
+}
+
+// guardrailAssessment is the part of a guardrail assessment that names the policies. Agent
+// traces and ApplyGuardrail report assessments with different types of the same shape, so
+// both are read into this one.
+type guardrailAssessment struct {
+	TopicPolicy *struct {
+		Topics []guardrailCheck
+	}
+	ContentPolicy *struct {
+		Filters []guardrailCheck
+	}
+	WordPolicy *struct {
+		CustomWords      []guardrailCheck
+		ManagedWordLists []guardrailCheck
+	}
+	SensitiveInformationPolicy *struct {
+		PiiEntities []guardrailCheck
+		Regexes     []guardrailCheck
+	}
+	ContextualGroundingPolicy *struct {
+		Filters []guardrailCheck
+	}
+}
+
+// blockedGuardrailPolicies lists the policies that blocked content in the guardrail
+// assessments of an agent trace or an ApplyGuardrail response
+func blockedGuardrailPolicies(assessments interface{}) []types.GuardrailPolicyMatch {
+	// Convert the assessments through JSON, which keeps the fields both types share
+	var converted []guardrailAssessment
+	content, err := json.Marshal(assessments)
+	if err == nil {
+		err = json.Unmarshal(content, &converted)
+	}
+	if err != nil {
+		utils.LogWarning(fmt.Sprintf("Could not read guardrail assessments: %v", err))
+		return nil
+	}
+
+	var policies []types.GuardrailPolicyMatch
+	for _, assessment := range converted {
+		if assessment.TopicPolicy != nil {
+			for _, topic := range assessment.TopicPolicy.Topics {
+				policies = addBlockedPolicy(policies, topic.Action, "denied topic", topic.Name)
+			}
+		}
+		if assessment.ContentPolicy != nil {
+			for _, filter := range assessment.ContentPolicy.Filters {
+				policies = addBlockedPolicy(policies, filter.Action, "content filter", guardrailEnumName(filter.Type))
+			}
+		}
+		if assessment.WordPolicy != nil {
+			for _, word := range assessment.WordPolicy.CustomWords {
+				policies = addBlockedPolicy(policies, word.Action, "word filter", "custom words")
+			}
+			for _, list := range assessment.WordPolicy.ManagedWordLists {
+				policies = addBlockedPolicy(policies, list.Action, "word filter", guardrailEnumName(list.Type))
+			}
+		}
+		if assessment.SensitiveInformationPolicy != nil {
+			for _, entity := range assessment.SensitiveInformationPolicy.PiiEntities {
+				policies = addBlockedPolicy(policies, entity.Action, "sensitive information", guardrailEnumName(entity.Type))
+			}
+			for _, regex := range assessment.SensitiveInformationPolicy.Regexes {
+				policies = addBlockedPolicy(policies, regex.Action, "sensitive information", regex.Name)
+			}
+		}
+		if assessment.ContextualGroundingPolicy != nil {
+			for _, filter := range assessment.ContextualGroundingPolicy.Filters {
+				policies = addBlockedPolicy(policies, filter.Action, "contextual grounding", guardrailEnumName(filter.Type))
+			}
+		}
+	}
+	return policies
+}
+
+// addBlockedPolicy adds a policy to the list if it blocked content and isn't listed yet
+func addBlockedPolicy(policies []types.GuardrailPolicyMatch, action, policy, name string) []types.GuardrailPolicyMatch {
+	if action != guardrailBlocked {
+		return policies
+	}
+
+	match := types.GuardrailPolicyMatch{Policy: policy, Name: name}
+	for _, existing := range policies {
+		if existing == match {
+			return policies
+		}
+	}
+	return append(policies, match)
+}
+
+// FormatGuardrailPolicies lists the policies that blocked content, e.g.
+// "denied topic (Investment advice), content filter (hate)"
+func FormatGuardrailPolicies(policies []types.GuardrailPolicyMatch) string {
+	parts := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		parts = append(parts, fmt.Sprintf("%s (%s)", policy.Policy, policy.Name))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// guardrailEnumName turns a guardrail enum value such as PROMPT_ATTACK into "prompt attack"
+func guardrailEnumName(value string) string {
+	return strings.ToLower(strings.ReplaceAll(value, "_", " "))
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"os"
+	"strconv"
+	"sync"
+
+	"slack-rag-server/src/types"
+)
+
+// defaultHistorySize is the number of entries kept per user when
+// RAGBOT_HISTORY_SIZE is not set
+const defaultHistorySize = 10
+
+// HistoryStore keeps the most recent questions and answers for each user in memory
+type HistoryStore struct {
+	mu      sync.RWMutex
+	entries map[string][]types.HistoryEntry
+	size    int
+}
+
+// NewHistoryStore creates a new HistoryStore
+func NewHistoryStore() *HistoryStore {
+	size := defaultHistorySize
+	if value := os.Getenv("RAGBOT_HISTORY_SIZE"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			size = parsed
+		}
+	}
+
+	return &HistoryStore{
+		entries: make(map[string][]types.HistoryEntry),
+		size:    size,
+	}
+}
+
+// Add records an entry for a user, dropping the oldest entry once the limit is reached
+func (s *HistoryStore) Add(userID string, entry types.HistoryEntry) {
+	if userID == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := append(s.entries[userID], entry)
+	if len(entries) > s.size {
+		entries = entries[len(entries)-s.size:]
+	}
+	s.entries[userID] = entries
+}
+
+// Recent returns up to limit entries for a user, newest first. A limit of 0 returns all entries.
+func (s *HistoryStore) Recent(userID string, limit int) []types.HistoryEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := s.entries[userID]
+	if limit <= 0 {
+		limit = len(entries)
+	}
+	result := []types.HistoryEntry{}
+	for i := len(entries) - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, entries[i])
+	}
+	return result
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	bedrockagent "github.com/aws/aws-sdk-go-v2/service/bedrockagent"
+	bedrockagent_types "github.com/aws/aws-sdk-go-v2/service/bedrockagent/types"
+
+	"slack-rag-server/src/types"
+)
+
+// DefaultJobHistorySize is the number of ingestion jobs listed when no count is requested
+const DefaultJobHistorySize = 10
+
+// MaxJobHistorySize is the largest number of ingestion jobs listed at once. Each job takes
+// three blocks, so a full page and its footer stay within Slack's 50 blocks per message.
+const MaxJobHistorySize = 15
+
+// failureDocumentPattern matches document identifiers (URIs and URLs) in ingestion failure reasons
+var failureDocumentPattern = regexp.MustCompile(`(?:s3|https?)://[^\s"'\],;)]+`)
+
+// ListIngestionJobs lists the most recent ingestion jobs of a data source (the configured one
+// when dataSourceID is empty), newest first. Pass the NextToken of a previous page to
+// continue listing older jobs.
+func (s *BedrockService) ListIngestionJobs(dataSourceID string, limit int, nextToken string) (interface{}, error) {
+	dataSourceID = s.dataSourceIDOrDefault(dataSourceID)
+	if s.knowledgeBaseID == "" || dataSourceID == "" {
+		return types.ErrorResponse{
+			Error: "Knowledge base ID or data source ID is not configured",
+		}, nil
+	}
+
+	if limit <= 0 {
+		limit = DefaultJobHistorySize
+	}
+	if limit > MaxJobHistorySize {
+		limit = MaxJobHistorySize
+	}
+
+	input := &bedrockagent.ListIngestionJobsInput{
+		KnowledgeBaseId: aws.String(s.knowledgeBaseID),
+		DataSourceId:    aws.String(dataSourceID),
+		MaxResults:      aws.Int32(int32(limit)),
+		SortBy: &bedrockagent_types.IngestionJobSortBy{
+			Attribute: bedrockagent_types.IngestionJobSortByAttributeStartedAt,
+			Order:     bedrockagent_types.SortOrderDescending,
+		},
+	}
+	if nextToken != "" {
+		input.NextToken = aws.String(nextToken)
+	}
+
+	resp, err := s.agentClient.ListIngestionJobs(context.Background(), input)
+	if err != nil {
+		return types.ErrorResponse{
+			Error:         err.Error(),
+			OriginalError: err,
+		}, nil
+	}
+
+	history := types.IngestionJobHistory{
+		Jobs:      []types.IngestionJobStatus{},
+		NextToken: aws.ToString(resp.NextToken),
+	}
+	for _, job := range resp.IngestionJobSummaries {
+		stats := convertIngestionStatistics(job.Statistics)
+		history.Jobs = append(history.Jobs, types.IngestionJobStatus{
+			IngestionJobID: aws.ToString(job.IngestionJobId),
+			DataSourceID:   aws.ToString(job.DataSourceId),
+			Status:         string(job.Status),
+			StartedAt:      aws.ToTime(job.StartedAt),
+			UpdatedAt:      aws.ToTime(job.UpdatedAt),
+			Statistics:     FormatIngestionStatistics(stats),
+			Stats:          stats,
+			RawResponse:    job,
+		})
+	}
+
+	return history, nil
+}
+
+// convertIngestionStatistics converts Bedrock ingestion statistics, treating missing statistics as zero
+func convertIngestionStatistics(statistics *bedrockagent_types.IngestionJobStatistics) types.IngestionJobStatistics {
+	if statistics == nil {
+		return types.IngestionJobStatistics{}
+	}
+
+	return types.IngestionJobStatistics{
+		DocumentsScanned:          statistics.NumberOfDocumentsScanned,
+		MetadataDocumentsScanned:  statistics.NumberOfMetadataDocumentsScanned,
+		MetadataDocumentsModified: statistics.NumberOfMetadataDocumentsModified,
+		NewDocumentsIndexed:       statistics.NumberOfNewDocumentsIndexed,
+		ModifiedDocumentsIndexed:  statistics.NumberOfModifiedDocumentsIndexed,
+		DocumentsDeleted:          statistics.NumberOfDocumentsDeleted,
+		DocumentsFailed:           statistics.NumberOfDocumentsFailed,
+	}
+}
+
+// FormatIngestionStatistics formats ingestion statistics as a single line
+func FormatIngestionStatistics(stats types.IngestionJobStatistics) string {
+	return fmt.Sprintf(
+		"%d scanned, %d new, %d modified, %d deleted, %d failed, %d metadata scanned, %d metadata modified",
+		stats.DocumentsScanned,
+		stats.NewDocumentsIndexed,
+		stats.ModifiedDocumentsIndexed,
+		stats.DocumentsDeleted,
+		stats.DocumentsFailed,
+		stats.MetadataDocumentsScanned,
+		stats.MetadataDocumentsModified,
+	)
+}
+
+// parseFailureReason extracts the documents named in an ingestion failure reason
+func parseFailureReason(reason string) types.IngestionFailure {
+	failure := types.IngestionFailure{Reason: strings.TrimSpace(reason)}
+
+	seen := map[string]bool{}
+	for _, document := range failureDocumentPattern.FindAllString(reason, -1) {
+		document = strings.TrimRight(document, ".")
+		if !seen[document] {
+			seen[document] = true
+			failure.Documents = append(failure.Documents, document)
+		}
+	}
+
+	return failure
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"slack-rag-server/src/types"
+)
+
+// RedactionMode decides what happens to a question containing sensitive data
+type RedactionMode string
+
+const (
+	// RedactionMask replaces sensitive data with a placeholder before the question is sent
+	RedactionMask RedactionMode = "mask"
+	// RedactionBlock refuses to send the question and warns the asker
+	RedactionBlock RedactionMode = "block"
+	// RedactionAllow sends the question unchanged
+	RedactionAllow RedactionMode = "allow"
+)
+
+// detector finds one kind of sensitive data. Matches are only redacted when valid is nil or
+// accepts them.
+type detector struct {
+	name    string
+	label   string
+	pattern *regexp.Regexp
+	valid   func(match string) bool
+}
+
+// builtinDetectors are the detectors available by name, in the order they are applied
+var builtinDetectors = []detector{
+	{
+		name:    "aws_key",
+		label:   "AWS access key",
+		pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b|(?i:aws_secret_access_key)["']?\s*[=:]\s*["']?[A-Za-z0-9/+=]{40}`),
+	},
+	{
+		name:    "slack_token",
+		label:   "Slack token",
+		pattern: regexp.MustCompile(`\bxox[abposr]-[0-9A-Za-z-]{10,}|https://hooks\.slack\.com/services/[A-Za-z0-9/]+`),
+	},
+	{
+		name:    "jwt",
+		label:   "JSON web token",
+		pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{5,}`),
+	},
+	{
+		name:    "credit_card",
+		label:   "credit card number",
+		pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		valid:   luhnValid,
+	},
+	{
+		name:    "email",
+		label:   "email address",
+		pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`),
+	},
+	{
+		name:    "phone",
+		label:   "phone number",
+		pattern: regexp.MustCompile(`\+\d{1,3}[ .-]?\d{1,4}(?:[ .-]?\d{2,4}){2,4}\b|(?:\(\d{3}\) ?|\b\d{3}[ .-])\d{3}[ .-]\d{4}\b`),
+	},
+}
+
+// customDetector is a detector defined in the redaction file
+type customDetector struct {
+	Name    string `json:"name"`
+	Label   string `json:"label,omitempty"`
+	Pattern string `json:"pattern"`
+}
+
+// redactionConfig is the JSON layout of RAGBOT_REDACTION_FILE
+type redactionConfig struct {
+	Default   RedactionMode            `json:"default"`
+	Channels  map[string]RedactionMode `json:"channels"`
+	Detectors []string                 `json:"detectors"`
+	Custom    []customDetector         `json:"custom"`
+}
+
+// Redactor finds secrets and personal data in questions before they leave Slack, and
+// decides per channel whether to mask them, block the question or allow it
+type Redactor struct {
+	config    redactionConfig
+	detectors []detector
+}
+
+// NewRedactor creates a Redactor from the JSON file named in RAGBOT_REDACTION_FILE.
+// Without the variable every built-in detector is used and questions are masked.
+func NewRedactor() (*Redactor, error) {
+	redactor := &Redactor{
+		config:    redactionConfig{Default: RedactionMask},
+		detectors: builtinDetectors,
+	}
+
+	path := os.Getenv("RAGBOT_REDACTION_FILE")
+	if path == "" {
+		return redactor, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction file: %w", err)
+	}
+	if err := json.Unmarshal(content, &redactor.config); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction file: %w", err)
+	}
+
+	// Validate the modes and detectors up front so mistakes surface at startup
+	if redactor.config.Default == "" {
+		redactor.config.Default = RedactionMask
+	}
+	if !validRedactionMode(redactor.config.Default) {
+		return nil, fmt.Errorf("invalid default redaction mode %q", redactor.config.Default)
+	}
+	for channel, mode := range redactor.config.Channels {
+		if !validRedactionMode(mode) {
+			return nil, fmt.Errorf("invalid redaction mode %q for channel %s", mode, channel)
+		}
+	}
+
+	if redactor.config.Detectors != nil {
+		redactor.detectors = nil
+		for _, name := range redactor.config.Detectors {
+			builtin, ok := builtinDetector(name)
+			if !ok {
+				return nil, fmt.Errorf("unknown redaction detector %q", name)
+			}
+			redactor.detectors = append(redactor.detectors, builtin)
+		}
+	}
+
+	for _, custom := range redactor.config.Custom {
+		if custom.Name == "" {
+			return nil, fmt.Errorf("custom redaction detector without a name")
+		}
+		pattern, err := regexp.Compile(custom.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for redaction detector %s: %w", custom.Name, err)
+		}
+		label := custom.Label
+		if label == "" {
+			label = custom.Name
+		}
+		redactor.detectors = append(redactor.detectors, detector{
+			name:    custom.Name,
+			label:   label,
+			pattern: pattern,
+		})
+	}
+
+	return redactor, nil
+}
+
+// validRedactionMode checks that a mode is one of mask, block or allow
+func validRedactionMode(mode RedactionMode) bool {
+	return mode == RedactionMask || mode == RedactionBlock || mode == RedactionAllow
+}
+
+// builtinDetector looks up a built-in detector by name
+func builtinDetector(name string) (detector, bool) {
+	for _, builtin := range builtinDetectors {
+		if builtin.name == name {
+			return builtin, true
+		}
+	}
+	return detector{}, false
+}
+
+// Mode returns the redaction mode for questions asked in a channel
+func (r *Redactor) Mode(channelID string) RedactionMode {
+	if mode, ok := r.config.Channels[channelID]; ok {
+		return mode
+	}
+	return r.config.Default
+}
+
+// Redact replaces every detected secret or piece of personal data with a placeholder naming
+// its detector, e.g. [REDACTED:email], and counts the detections
+func (r *Redactor) Redact(text string) types.RedactionResult {
+	result := types.RedactionResult{Text: text}
+
+	for _, d := range r.detectors {
+		result.Text = d.pattern.ReplaceAllStringFunc(result.Text, func(match string) string {
+			if d.valid != nil && !d.valid(match) {
+				return match
+			}
+			if result.Detections == nil {
+				result.Detections = map[string]int{}
+			}
+			result.Detections[d.name]++
+			return "[REDACTED:" + d.name + "]"
+		})
+	}
+
+	return result
+}
+
+// DescribeDetections lists the kinds of data detected, e.g. "AWS access key, email address"
+func (r *Redactor) DescribeDetections(detections map[string]int) string {
+	var labels []string
+	for _, d := range r.detectors {
+		if detections[d.name] > 0 {
+			labels = append(labels, d.label)
+		}
+	}
+	return strings.Join(labels, ", ")
+}
+
+// SummarizeDetections counts the detections for the log, e.g. "email=2 jwt=1"
+func SummarizeDetections(detections map[string]int) string {
+	names := make([]string, 0, len(detections))
+	for name := range detections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%d", name, detections[name]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// luhnValid checks a candidate card number with the Luhn checksum, ignoring spaces and dashes
+func luhnValid(number string) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		digit := int(c - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	bedrockagentruntime "github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime/document"
+	bedrockagentruntime_types "github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime/types"
+
+	"slack-rag-server/src/types"
+)
+
+// DefaultRetrieveResults is the number of passages returned when no top-k is requested
+const DefaultRetrieveResults = 5
+
+// MaxRetrieveResults is the largest top-k accepted for a retrieval, so that the results fit
+// in a single Slack message at three blocks per passage
+const MaxRetrieveResults = 16
+
+// RetrieveFromKnowledgeBase queries the knowledge base directly, returning the raw
+// matching passages without invoking the agent
+func (s *BedrockService) RetrieveFromKnowledgeBase(query string, topK int, filters []types.MetadataFilter) (interface{}, error) {
+	if s.knowledgeBaseID == "" {
+		return types.ErrorResponse{
+			Error: "Knowledge base ID is not configured",
+		}, nil
+	}
+
+	if topK <= 0 {
+		topK = DefaultRetrieveResults
+	}
+	if topK > MaxRetrieveResults {
+		topK = MaxRetrieveResults
+	}
+
+	retrievalConfig, err := s.retrievalConfiguration(topK, filters)
+	if err != nil {
+		return types.ErrorResponse{
+			Error:         err.Error(),
+			OriginalError: err,
+		}, nil
+	}
+
+	input := &bedrockagentruntime.RetrieveInput{
+		KnowledgeBaseId: aws.String(s.knowledgeBaseID),
+		RetrievalQuery: &bedrockagentruntime_types.KnowledgeBaseQuery{
+			Text: aws.String(query),
+		},
+		RetrievalConfiguration: retrievalConfig,
+	}
+
+	resp, err := s.agentRuntimeClient.Retrieve(context.Background(), input)
+	if err != nil {
+		return types.ErrorResponse{
+			Error:         err.Error(),
+			OriginalError: err,
+		}, nil
+	}
+
+	results := []types.RetrievalResult{}
+	for _, result := range resp.RetrievalResults {
+		retrieved := types.RetrievalResult{
+			Source:   retrievalSource(result.Location),
+			Metadata: map[string]interface{}{},
+		}
+		if result.Content != nil && result.Content.Text != nil {
+			retrieved.Text = *result.Content.Text
+		}
+		if result.Score != nil {
+			retrieved.Score = *result.Score
+		}
+		for key, value := range result.Metadata {
+			var decoded interface{}
+			if err := value.UnmarshalSmithyDocument(&decoded); err == nil {
+				retrieved.Metadata[key] = decoded
+			}
+		}
+		results = append(results, retrieved)
+	}
+
+	return types.RetrievalResults{
+		Query:   query,
+		Results: results,
+	}, nil
+}
+
+// retrievalConfiguration builds a vector search configuration with the given number of
+// results (or the service default when topK is 0) and metadata filters
+func (s *BedrockService) retrievalConfiguration(topK int, filters []types.MetadataFilter) (*bedrockagentruntime_types.KnowledgeBaseRetrievalConfiguration, error) {
+	filter, err := BuildRetrievalFilter(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	vectorSearch := &bedrockagentruntime_types.KnowledgeBaseVectorSearchConfiguration{
+		Filter: filter,
+	}
+	if topK > 0 {
+		vectorSearch.NumberOfResults = aws.Int32(int32(topK))
+	}
+
+	return &bedrockagentruntime_types.KnowledgeBaseRetrievalConfiguration{
+		VectorSearchConfiguration: vectorSearch,
+	}, nil
+}
+
+// BuildRetrievalFilter converts metadata filters into a Bedrock retrieval filter. Multiple
+// filters are combined with AND. It returns nil when there are no filters.
+func BuildRetrievalFilter(filters []types.MetadataFilter) (bedrockagentruntime_types.RetrievalFilter, error) {
+	var members []bedrockagentruntime_types.RetrievalFilter
+	for _, filter := range filters {
+		member, err := buildFilterMember(filter)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	switch len(members) {
+	case 0:
+		return nil, nil
+	case 1:
+		return members[0], nil
+	default:
+		return &bedrockagentruntime_types.RetrievalFilterMemberAndAll{Value: members}, nil
+	}
+}
+
+// buildFilterMember converts a single metadata filter into a Bedrock retrieval filter
+func buildFilterMember(filter types.MetadataFilter) (bedrockagentruntime_types.RetrievalFilter, error) {
+	if filter.Key == "" || len(filter.Values) == 0 {
+		return nil, fmt.Errorf("metadata filter must have a key and at least one value")
+	}
+
+	switch filter.Operator {
+	case "equals", "":
+		return &bedrockagentruntime_types.RetrievalFilterMemberEquals{Value: bedrockagentruntime_types.FilterAttribute{
+			Key:   aws.String(filter.Key),
+			Value: document.NewLazyDocument(filter.Values[0]),
+		}}, nil
+	case "in":
+		return &bedrockagentruntime_types.RetrievalFilterMemberIn{Value: bedrockagentruntime_types.FilterAttribute{
+			Key:   aws.String(filter.Key),
+			Value: document.NewLazyDocument(filter.Values),
+		}}, nil
+	case "startsWith":
+		return &bedrockagentruntime_types.RetrievalFilterMemberStartsWith{Value: bedrockagentruntime_types.FilterAttribute{
+			Key:   aws.String(filter.Key),
+			Value: document.NewLazyDocument(filter.Values[0]),
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported metadata filter operator %q", filter.Operator)
+	}
+}
+
+// retrievalSource returns a link or identifier for where a retrieved passage came from
+func retrievalSource(location *bedrockagentruntime_types.RetrievalResultLocation) string {
+	if location == nil {
+		return ""
+	}
+
+	var source *string
+	switch {
+	case location.S3Location != nil:
+		source = location.S3Location.Uri
+	case location.WebLocation != nil:
+		source = location.WebLocation.Url
+	case location.ConfluenceLocation != nil:
+		source = location.ConfluenceLocation.Url
+	case location.SharePointLocation != nil:
+		source = location.SharePointLocation.Url
+	case location.SalesforceLocation != nil:
+		source = location.SalesforceLocation.Url
+	case location.KendraDocumentLocation != nil:
+		source = location.KendraDocumentLocation.Uri
+	case location.CustomDocumentLocation != nil:
+		source = location.CustomDocumentLocation.Id
+	}
+
+	if source == nil {
+		return ""
+	}
+	return *source
+}
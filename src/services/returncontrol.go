@@ -0,0 +1,158 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	bedrockagentruntime_types "github.com/aws/aws-sdk-go-v2/service/bedrockagentruntime/types"
+
+	"slack-rag-server/src/types"
+	"slack-rag-server/src/utils"
+)
+
+// maxReturnControlRounds is how many times in a row the agent may return control before
+// the request is abandoned
+const maxReturnControlRounds = 10
+
+// runReturnControlActions runs every action in a return-control payload through the action
+// registry and converts the outcomes into invocation results for the agent, one per input.
+// Actions that need user approval are passed to confirm first and reported as denied when
+// it is nil. Inputs of unsupported types are reported as failed. The asker's identity
+// attributes name the user and channel in the audit trail.
+func (s *BedrockService) runReturnControlActions(sessionID string, payload *bedrockagentruntime_types.ReturnControlPayload, sessionContext types.SessionContext) []bedrockagentruntime_types.InvocationResultMember {
+	results := []bedrockagentruntime_types.InvocationResultMember{}
+	confirm := sessionContext.ConfirmAction
+	user := sessionContext.SessionAttributes[UserIDAttribute]
+	channel := sessionContext.SessionAttributes[ChannelIDAttribute]
+
+	for _, input := range payload.InvocationInputs {
+		invocation, ok := actionInvocation(input)
+		if !ok {
+			err := fmt.Errorf("unsupported return control input of type %T", input)
+			utils.LogWarning(err.Error())
+			results = append(results, invocationResult(invocation, "The action failed: "+err.Error(), err, ""))
+			continue
+		}
+
+		var confirmation bedrockagentruntime_types.ConfirmationState
+		if requiresConfirmation(invocation) {
+			approved, decision := false, "no way to ask the user for approval"
+			if confirm != nil {
+				approved, decision = confirm(invocation)
+			}
+			s.actions.RecordConfirmation(sessionID, user, channel, invocation, approved, decision)
+
+			if !approved {
+				results = append(results, invocationResult(invocation, "The user did not approve this action: "+decision, nil, bedrockagentruntime_types.ConfirmationStateDeny))
+				continue
+			}
+			confirmation = bedrockagentruntime_types.ConfirmationStateConfirm
+
+			// The agent's own executor runs the action once it is confirmed
+			if invocation.InvocationType == string(bedrockagentruntime_types.ActionInvocationTypeUserConfirmation) {
+				results = append(results, invocationResult(invocation, "The user approved this action.", nil, confirmation))
+				continue
+			}
+		}
+
+		var result string
+		var err error
+		if s.actions == nil {
+			err = fmt.Errorf("no local actions are configured")
+		} else {
+			result, err = s.actions.Execute(sessionID, user, channel, invocation)
+		}
+		if err != nil {
+			utils.LogWarning(fmt.Sprintf("Action %s/%s failed: %v", invocation.ActionGroup, ActionName(invocation), err))
+			result = fmt.Sprintf("The action failed: %v", err)
+		}
+
+		results = append(results, invocationResult(invocation, result, err, confirmation))
+	}
+
+	return results
+}
+
+// requiresConfirmation checks whether the agent needs the user to approve an action
+func requiresConfirmation(invocation types.ActionInvocation) bool {
+	switch bedrockagentruntime_types.ActionInvocationType(invocation.InvocationType) {
+	case bedrockagentruntime_types.ActionInvocationTypeUserConfirmation,
+		bedrockagentruntime_types.ActionInvocationTypeUserConfirmationAndResult:
+		return true
+	default:
+		return false
+	}
+}
+
+// actionInvocation converts a return-control input into an ActionInvocation
+func actionInvocation(input bedrockagentruntime_types.InvocationInputMember) (types.ActionInvocation, bool) {
+	invocation := types.ActionInvocation{Parameters: map[string]string{}}
+
+	switch v := input.(type) {
+	case *bedrockagentruntime_types.InvocationInputMemberMemberFunctionInvocationInput:
+		invocation.ActionGroup = aws.ToString(v.Value.ActionGroup)
+		invocation.Function = aws.ToString(v.Value.Function)
+		invocation.InvocationType = string(v.Value.ActionInvocationType)
+		for _, parameter := range v.Value.Parameters {
+			invocation.Parameters[aws.ToString(parameter.Name)] = aws.ToString(parameter.Value)
+		}
+	case *bedrockagentruntime_types.InvocationInputMemberMemberApiInvocationInput:
+		invocation.ActionGroup = aws.ToString(v.Value.ActionGroup)
+		invocation.APIPath = aws.ToString(v.Value.ApiPath)
+		invocation.HTTPMethod = aws.ToString(v.Value.HttpMethod)
+		invocation.InvocationType = string(v.Value.ActionInvocationType)
+		for _, parameter := range v.Value.Parameters {
+			invocation.Parameters[aws.ToString(parameter.Name)] = aws.ToString(parameter.Value)
+		}
+		if v.Value.RequestBody != nil {
+			for _, content := range v.Value.RequestBody.Content {
+				for _, property := range content.Properties {
+					invocation.Parameters[aws.ToString(property.Name)] = aws.ToString(property.Value)
+				}
+			}
+		}
+	default:
+		return invocation, false
+	}
+
+	return invocation, true
+}
+
+// invocationResult builds the result sent back to the agent for an action, along with the
+// user's decision when the action needed confirmation
+func invocationResult(invocation types.ActionInvocation, result string, err error, confirmation bedrockagentruntime_types.ConfirmationState) bedrockagentruntime_types.InvocationResultMember {
+	if invocation.Function != "" {
+		functionResult := bedrockagentruntime_types.FunctionResult{
+			ActionGroup:       aws.String(invocation.ActionGroup),
+			Function:          aws.String(invocation.Function),
+			ConfirmationState: confirmation,
+			ResponseBody: map[string]bedrockagentruntime_types.ContentBody{
+				"TEXT": {Body: aws.String(result)},
+			},
+		}
+		if err != nil {
+			functionResult.ResponseState = bedrockagentruntime_types.ResponseStateFailure
+		}
+		return &bedrockagentruntime_types.InvocationResultMemberMemberFunctionResult{Value: functionResult}
+	}
+
+	statusCode := http.StatusOK
+	if err != nil {
+		statusCode = http.StatusInternalServerError
+	}
+	apiResult := bedrockagentruntime_types.ApiResult{
+		ActionGroup:       aws.String(invocation.ActionGroup),
+		ApiPath:           aws.String(invocation.APIPath),
+		HttpMethod:        aws.String(invocation.HTTPMethod),
+		HttpStatusCode:    aws.Int32(int32(statusCode)),
+		ConfirmationState: confirmation,
+		ResponseBody: map[string]bedrockagentruntime_types.ContentBody{
+			"application/json": {Body: aws.String(result)},
+		},
+	}
+	if err != nil {
+		apiResult.ResponseState = bedrockagentruntime_types.ResponseStateFailure
+	}
+	return &bedrockagentruntime_types.InvocationResultMemberMemberApiResult{Value: apiResult}
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+
+	"slack-rag-server/src/types"
+	"slack-rag-server/src/utils"
+)
+
+// defaultSyncMonitorMinutes is how long a scheduled sync is monitored when the schedule does not say
+const defaultSyncMonitorMinutes = 60
+
+// syncScheduleConfig is an entry of RAGBOT_SYNC_SCHEDULES_FILE
+type syncScheduleConfig struct {
+	Name           string `json:"name"`
+	DataSourceID   string `json:"dataSourceId"`
+	Cron           string `json:"cron"`
+	Timezone       string `json:"timezone"`
+	MaxWaitMinutes int    `json:"maxWaitMinutes"`
+}
+
+// scheduledSync is a sync schedule along with its runtime state
+type scheduledSync struct {
+	config     syncScheduleConfig
+	schedule   *utils.CronSchedule
+	location   *time.Location
+	paused     bool
+	running    bool
+	nextRun    time.Time
+	lastRun    time.Time
+	lastResult string
+}
+
+// SyncScheduler starts data source syncs on cron schedules, monitors them to completion and
+// posts the outcome to the ops channel named in RAGBOT_OPS_CHANNEL
+type SyncScheduler struct {
+	bedrockService *BedrockService
+	api            *slack.Client
+	opsChannel     string
+
+	mu    sync.Mutex
+	syncs []*scheduledSync
+}
+
+// NewSyncScheduler creates a SyncScheduler from the JSON file named in RAGBOT_SYNC_SCHEDULES_FILE.
+// Without the variable there are no schedules.
+func NewSyncScheduler(bedrockService *BedrockService, api *slack.Client) (*SyncScheduler, error) {
+	scheduler := &SyncScheduler{
+		bedrockService: bedrockService,
+		api:            api,
+		opsChannel:     os.Getenv("RAGBOT_OPS_CHANNEL"),
+	}
+
+	path := os.Getenv("RAGBOT_SYNC_SCHEDULES_FILE")
+	if path == "" {
+		return scheduler, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync schedules file: %w", err)
+	}
+
+	var configs []syncScheduleConfig
+	if err := json.Unmarshal(content, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse sync schedules file: %w", err)
+	}
+
+	names := map[string]bool{}
+	for _, config := range configs {
+		if config.Name == "" || config.Cron == "" {
+			return nil, fmt.Errorf("sync schedule must have a name and a cron expression")
+		}
+		if names[strings.ToLower(config.Name)] {
+			return nil, fmt.Errorf("duplicate sync schedule name %q", config.Name)
+		}
+		names[strings.ToLower(config.Name)] = true
+
+		schedule, err := utils.ParseCron(config.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("sync schedule %q: %w", config.Name, err)
+		}
+
+		location := time.UTC
+		if config.Timezone != "" {
+			location, err = time.LoadLocation(config.Timezone)
+			if err != nil {
+				return nil, fmt.Errorf("sync schedule %q: invalid timezone: %w", config.Name, err)
+			}
+		}
+
+		if config.MaxWaitMinutes <= 0 {
+			config.MaxWaitMinutes = defaultSyncMonitorMinutes
+		}
+
+		scheduler.syncs = append(scheduler.syncs, &scheduledSync{
+			config:   config,
+			schedule: schedule,
+			location: location,
+			nextRun:  schedule.Next(time.Now().In(location)),
+		})
+	}
+
+	return scheduler, nil
+}
+
+// Start runs the scheduler in the background, checking the schedules every minute
+func (s *SyncScheduler) Start() {
+	if len(s.syncs) == 0 {
+		return
+	}
+
+	utils.LogInfo(fmt.Sprintf("Starting sync scheduler with %d schedules", len(s.syncs)))
+	go func() {
+		for {
+			// Wake up at the start of each minute
+			now := time.Now()
+			time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+			s.runDue(time.Now())
+		}
+	}()
+}
+
+// runDue starts every sync whose next run time has passed
+func (s *SyncScheduler) runDue(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, scheduled := range s.syncs {
+		if scheduled.nextRun.IsZero() || now.Before(scheduled.nextRun) {
+			continue
+		}
+		scheduled.nextRun = scheduled.schedule.Next(now.In(scheduled.location))
+
+		if scheduled.paused || scheduled.running {
+			continue
+		}
+		scheduled.running = true
+		scheduled.lastRun = now
+		go s.run(scheduled)
+	}
+}
+
+// run starts a scheduled sync unless a job is already running, then monitors it and
+// reports the outcome
+func (s *SyncScheduler) run(scheduled *scheduledSync) {
+	result := s.sync(scheduled.config)
+	utils.LogInfo(fmt.Sprintf("Scheduled sync %s: %s", scheduled.config.Name, result))
+
+	s.mu.Lock()
+	scheduled.running = false
+	scheduled.lastResult = result
+	s.mu.Unlock()
+
+	s.notify(fmt.Sprintf("*Scheduled sync `%s`*\n%s", scheduled.config.Name, result))
+}
+
+// sync performs a scheduled sync and returns a summary of the outcome
+func (s *SyncScheduler) sync(config syncScheduleConfig) string {
+	dataSourceID, err := s.bedrockService.ResolveDataSource(config.DataSourceID)
+	if err != nil {
+		return "❌ " + err.Error()
+	}
+
+	inProgress, err := s.jobInProgress(dataSourceID)
+	if err != nil {
+		return "❌ Could not check for running ingestion jobs: " + err.Error()
+	}
+	if inProgress != "" {
+		return fmt.Sprintf("⏭️ Skipped, ingestion job `%s` is already in progress", inProgress)
+	}
+
+	response, err := s.bedrockService.SyncDataSource(dataSourceID, "Scheduled ("+config.Name+")")
+	if err != nil {
+		return "❌ Failed to start sync: " + err.Error()
+	}
+	if errorResp, ok := response.(types.ErrorResponse); ok {
+		return "❌ Failed to start sync: " + errorResp.Error
+	}
+	dsSync, ok := response.(types.DataSourceSync)
+	if !ok {
+		return fmt.Sprintf("❌ Unexpected sync response: %v", response)
+	}
+
+	response, err = s.bedrockService.MonitorIngestionJob(dsSync.DataSourceID, dsSync.IngestionJobID, config.MaxWaitMinutes)
+	if err != nil {
+		return fmt.Sprintf("❌ Job `%s` could not be monitored: %v", dsSync.IngestionJobID, err)
+	}
+	if errorResp, ok := response.(types.ErrorResponse); ok {
+		return fmt.Sprintf("❌ Job `%s` could not be monitored: %s", dsSync.IngestionJobID, errorResp.Error)
+	}
+	monitor, ok := response.(types.MonitorIngestionJobStatus)
+	if !ok {
+		return fmt.Sprintf("❌ Unexpected monitoring response: %v", response)
+	}
+
+	status := "✅"
+	if !monitor.Success {
+		status = "❌"
+	}
+	summary := fmt.Sprintf("%s Job `%s` on data source `%s` finished with status %s: %s",
+		status, monitor.IngestionJobID, monitor.DataSourceID, monitor.JobStatus, monitor.Message)
+
+	// Add the document counts and failures of the finished job
+	response, err = s.bedrockService.GetIngestionJobStatus(dsSync.DataSourceID, dsSync.IngestionJobID)
+	if jobStatus, ok := response.(types.IngestionJobStatus); err == nil && ok {
+		summary += "\nDocuments: " + jobStatus.Statistics
+		if len(jobStatus.FailureReasons) > 0 {
+			summary += fmt.Sprintf("\n%d failure reasons, see `/ragbot-job-status %s`", len(jobStatus.FailureReasons), jobStatus.IngestionJobID)
+		}
+	}
+
+	return summary
+}
+
+// jobInProgress returns the ID of an ingestion job still running on a data source, if any
+func (s *SyncScheduler) jobInProgress(dataSourceID string) (string, error) {
+	response, err := s.bedrockService.ListIngestionJobs(dataSourceID, 5, "")
+	if err != nil {
+		return "", err
+	}
+	if errorResp, ok := response.(types.ErrorResponse); ok {
+		return "", fmt.Errorf("%s", errorResp.Error)
+	}
+
+	history, ok := response.(types.IngestionJobHistory)
+	if !ok {
+		return "", fmt.Errorf("unexpected response: %v", response)
+	}
+
+	for _, job := range history.Jobs {
+		if job.Status == "STARTING" || job.Status == "IN_PROGRESS" || job.Status == "STOPPING" {
+			return job.IngestionJobID, nil
+		}
+	}
+	return "", nil
+}
+
+// notify posts a message to the ops channel, if one is configured
+func (s *SyncScheduler) notify(text string) {
+	if s.opsChannel == "" {
+		return
+	}
+
+	if err := utils.SendSlackMessage(s.api, s.opsChannel, text, ""); err != nil {
+		utils.LogError(err, "Error posting to ops channel")
+	}
+}
+
+// List returns the status of every schedule, ordered by name
+func (s *SyncScheduler) List() []types.SyncScheduleStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := []types.SyncScheduleStatus{}
+	for _, scheduled := range s.syncs {
+		statuses = append(statuses, types.SyncScheduleStatus{
+			Name:         scheduled.config.Name,
+			DataSourceID: scheduled.config.DataSourceID,
+			Cron:         scheduled.config.Cron,
+			Timezone:     scheduled.location.String(),
+			Paused:       scheduled.paused,
+			Running:      scheduled.running,
+			NextRun:      scheduled.nextRun,
+			LastRun:      scheduled.lastRun,
+			LastResult:   scheduled.lastResult,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// Pause stops a schedule from starting new syncs until it is resumed
+func (s *SyncScheduler) Pause(name string) error {
+	return s.setPaused(name, true)
+}
+
+// Resume lets a paused schedule start syncs again
+func (s *SyncScheduler) Resume(name string) error {
+	return s.setPaused(name, false)
+}
+
+// setPaused pauses or resumes a schedule by name
+func (s *SyncScheduler) setPaused(name string, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var names []string
+	for _, scheduled := range s.syncs {
+		if strings.EqualFold(scheduled.config.Name, name) {
+			scheduled.paused = paused
+			return nil
+		}
+		names = append(names, scheduled.config.Name)
+	}
+
+	if len(names) == 0 {
+		return fmt.Errorf("no sync schedules are configured")
+	}
+	return fmt.Errorf("unknown schedule %q, expected one of: %s", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"slack-rag-server/src/utils"
+)
+
+// sessionIdleTTL is how long a thread's session is kept after its last question. It is the
+// longest idle session TTL Bedrock allows, after which the agent has forgotten the session.
+const sessionIdleTTL = time.Hour
+
+// sessionSaveInterval is how often the last use of an otherwise unchanged session is saved
+const sessionSaveInterval = 10 * time.Minute
+
+// SessionStore maps Slack threads to Bedrock agent session IDs and the agent alias each
+// session talks to. Threads are identified by channel and timestamp, and use "channel:ts" as
+// the session ID until the session is rotated. Sessions idle for longer than Bedrock keeps
+// them are dropped. Sessions are saved to the JSON file named in RAGBOT_SESSIONS_FILE so
+// they survive restarts.
+type SessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]threadSession
+	path     string
+}
+
+// threadSession is the agent session of a thread. An empty AliasID is the configured alias.
+type threadSession struct {
+	SessionID string    `json:"sessionId,omitempty"`
+	AliasID   string    `json:"aliasId,omitempty"`
+	UsedAt    time.Time `json:"usedAt"`
+
+	// savedAt is when the session was last written to the sessions file
+	savedAt time.Time
+}
+
+// ThreadKey identifies a thread across channels as "channel:ts"
+func ThreadKey(channel, thread string) string {
+	return channel + ":" + thread
+}
+
+// NewSessionStore creates a new SessionStore, loading saved sessions from RAGBOT_SESSIONS_FILE
+func NewSessionStore() (*SessionStore, error) {
+	store := &SessionStore{
+		sessions: make(map[string]threadSession),
+		path:     os.Getenv("RAGBOT_SESSIONS_FILE"),
+	}
+
+	if store.path == "" {
+		return store, nil
+	}
+
+	content, err := os.ReadFile(store.path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sessions file: %w", err)
+	}
+
+	var saved map[string]json.RawMessage
+	if err := json.Unmarshal(content, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse sessions file: %w", err)
+	}
+	for key, value := range saved {
+		// Files written before sessions were keyed by channel cannot tell threads apart
+		if !strings.Contains(key, ":") {
+			utils.LogWarning(fmt.Sprintf("Dropping saved session of thread %s without a channel", key))
+			continue
+		}
+
+		var session threadSession
+		if err := json.Unmarshal(value, &session); err != nil {
+			return nil, fmt.Errorf("failed to parse session of thread %s: %w", key, err)
+		}
+		if time.Since(session.UsedAt) <= sessionIdleTTL {
+			session.savedAt = session.UsedAt
+			store.sessions[key] = session
+		}
+	}
+
+	return store, nil
+}
+
+// session returns the unexpired session of a thread. The caller must hold the lock.
+func (s *SessionStore) session(key string) (threadSession, bool) {
+	session, ok := s.sessions[key]
+	if !ok || time.Since(session.UsedAt) > sessionIdleTTL {
+		return threadSession{}, false
+	}
+	return session, true
+}
+
+// SessionID returns the agent session ID for a thread
+func (s *SessionStore) SessionID(key string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if session, ok := s.session(key); ok && session.SessionID != "" {
+		return session.SessionID
+	}
+	return key
+}
+
+// AliasID returns the agent alias the thread's session talks to, empty for the configured alias
+func (s *SessionStore) AliasID(key string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, _ := s.session(key)
+	return session.AliasID
+}
+
+// Use records a question in a thread and the agent alias its session talks to, so the
+// session can be ended on that alias. The file is only written when the alias changes or
+// the last saved use is getting old.
+func (s *SessionStore) Use(key, aliasID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, _ := s.session(key)
+	aliasChanged := session.AliasID != aliasID
+	session.AliasID = aliasID
+	session.UsedAt = time.Now()
+
+	if !aliasChanged && time.Since(session.savedAt) <= sessionSaveInterval {
+		s.sessions[key] = session
+		return nil
+	}
+	session.savedAt = session.UsedAt
+	s.sessions[key] = session
+	return s.save()
+}
+
+// Rotate assigns a new session ID to a thread and returns it
+func (s *SessionStore) Rotate(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessionID := fmt.Sprintf("%s-%d", key, time.Now().UnixNano())
+	now := time.Now()
+	s.sessions[key] = threadSession{SessionID: sessionID, UsedAt: now, savedAt: now}
+	return sessionID, s.save()
+}
+
+// save drops idle sessions and writes the rest to the sessions file, replacing it atomically.
+// The caller must hold the lock.
+func (s *SessionStore) save() error {
+	for key, session := range s.sessions {
+		if time.Since(session.UsedAt) > sessionIdleTTL {
+			delete(s.sessions, key)
+		}
+	}
+
+	if s.path == "" {
+		return nil
+	}
+
+	content, err := json.Marshal(s.sessions)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save sessions: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save sessions: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save sessions: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to save sessions: %w", err)
+	}
+	return nil
+}
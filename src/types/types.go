@@ -12,8 +12,65 @@ type ErrorResponse struct {
 
 // AgentResponse represents a response from the Bedrock agent
 type AgentResponse struct {
-	Response  string `json:"response,omitempty"`
-	Traceback string `json:"traceback,omitempty"`
+	Response  string                 `json:"response,omitempty"`
+	Traceback string                 `json:"traceback,omitempty"`
+	Citations []Citation             `json:"citations,omitempty"`
+	Guardrail *GuardrailIntervention `json:"guardrail,omitempty"`
+}
+
+// GuardrailIntervention describes a question or answer blocked by a Bedrock guardrail.
+// Source is where the intervention was reported: "agent" (a trace event),
+// "retrieve_and_generate" or "apply_guardrail" (the standalone check). Stage is "input"
+// or "output" when known.
+type GuardrailIntervention struct {
+	Source   string                 `json:"source"`
+	Stage    string                 `json:"stage,omitempty"`
+	Policies []GuardrailPolicyMatch `json:"policies,omitempty"`
+}
+
+// GuardrailPolicyMatch is a guardrail policy that blocked content, e.g. the "denied topic"
+// policy for the topic "Investment advice". The matched text is never kept.
+type GuardrailPolicyMatch struct {
+	Policy string `json:"policy"`
+	Name   string `json:"name"`
+}
+
+// Citation represents a part of an answer and the sources it was generated from
+type Citation struct {
+	Text    string   `json:"text"`
+	Sources []string `json:"sources"`
+}
+
+// SessionContext carries additional Slack context sent to the agent with a request
+type SessionContext struct {
+	SessionAttributes       map[string]string `json:"sessionAttributes,omitempty"`
+	PromptSessionAttributes map[string]string `json:"promptSessionAttributes,omitempty"`
+	AgentAliasID            string            `json:"agentAliasId,omitempty"`
+	KnowledgeBaseFilters    []MetadataFilter  `json:"knowledgeBaseFilters,omitempty"`
+	ConfirmAction           ActionConfirmer   `json:"-"`
+	OnChunk                 func(text string) `json:"-"`
+}
+
+// ActionConfirmer asks the user to approve an agent action, blocking until they decide
+// or the request expires. It returns whether the action was approved and a description
+// of the decision for the audit trail.
+type ActionConfirmer func(invocation ActionInvocation) (approved bool, decision string)
+
+// ParsedMessage represents a question with its inline flags extracted
+type ParsedMessage struct {
+	Text         string `json:"text"`
+	Traceback    bool   `json:"traceback"`
+	Agent        string `json:"agent,omitempty"`
+	Private      bool   `json:"private"`
+	NewSession   bool   `json:"newSession"`
+	RetrieveOnly bool   `json:"retrieveOnly"`
+}
+
+// RedactionResult is a text with its sensitive data masked and the number of matches
+// of each detector
+type RedactionResult struct {
+	Text       string         `json:"text"`
+	Detections map[string]int `json:"detections,omitempty"`
 }
 
 // FileAttachment represents a file attached to a message
@@ -34,6 +91,52 @@ type AgentStatus struct {
 	RawResponse     interface{} `json:"rawResponse,omitempty"`
 }
 
+// AgentVersion represents a version of the agent
+type AgentVersion struct {
+	Version     string      `json:"version"`
+	Name        string      `json:"name"`
+	Status      string      `json:"status"`
+	Description string      `json:"description,omitempty"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	UpdatedAt   time.Time   `json:"updatedAt"`
+	RawResponse interface{} `json:"rawResponse,omitempty"`
+}
+
+// AgentVersionList represents the versions of the agent, newest first
+type AgentVersionList struct {
+	Versions []AgentVersion `json:"versions"`
+	Count    int            `json:"count"`
+}
+
+// AgentAlias represents an alias of the agent and the versions it routes to
+type AgentAlias struct {
+	AliasID     string      `json:"aliasId"`
+	Name        string      `json:"name"`
+	Status      string      `json:"status"`
+	Description string      `json:"description,omitempty"`
+	Versions    []string    `json:"versions"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	UpdatedAt   time.Time   `json:"updatedAt"`
+	RawResponse interface{} `json:"rawResponse,omitempty"`
+}
+
+// AgentAliasList represents the aliases of the agent
+type AgentAliasList struct {
+	Aliases []AgentAlias `json:"aliases"`
+	Count   int          `json:"count"`
+}
+
+// AgentPreparation represents the outcome of preparing the agent or updating one of its aliases
+type AgentPreparation struct {
+	Success    bool      `json:"success"`
+	AgentID    string    `json:"agentId"`
+	AliasID    string    `json:"aliasId,omitempty"`
+	Status     string    `json:"status"`
+	Version    string    `json:"version,omitempty"`
+	PreparedAt time.Time `json:"preparedAt"`
+	Message    string    `json:"message"`
+}
+
 // KnowledgeBaseStatus represents the status of a Bedrock knowledge base
 type KnowledgeBaseStatus struct {
 	Name        string      `json:"name"`
@@ -71,9 +174,23 @@ type DataSourceSync struct {
 	KnowledgeBaseID string      `json:"knowledgeBaseId"`
 	IngestionJobID  string      `json:"ingestionJobId"`
 	Status          string      `json:"status"`
+	Name            string      `json:"name,omitempty"`
 	RawResponse     interface{} `json:"rawResponse,omitempty"`
 }
 
+// DataSourceSyncFailure represents a data source that could not be synced
+type DataSourceSyncFailure struct {
+	DataSourceID string `json:"dataSourceId"`
+	Name         string `json:"name"`
+	Error        string `json:"error"`
+}
+
+// DataSourceSyncBatch represents the result of syncing every data source of a knowledge base
+type DataSourceSyncBatch struct {
+	Jobs     []DataSourceSync        `json:"jobs"`
+	Failures []DataSourceSyncFailure `json:"failures,omitempty"`
+}
+
 // DataSource represents a data source
 type DataSource struct {
 	DataSourceID string      `json:"dataSourceId"`
@@ -91,13 +208,39 @@ type DataSourceList struct {
 
 // IngestionJobStatus represents the status of an ingestion job
 type IngestionJobStatus struct {
-	IngestionJobID  string      `json:"ingestionJobId"`
-	Status          string      `json:"status"`
-	StartedAt       time.Time   `json:"startedAt"`
-	UpdatedAt       time.Time   `json:"updatedAt"`
-	Statistics      string      `json:"statistics"`
-	FailureReasons  []string    `json:"failureReasons"`
-	RawResponse     interface{} `json:"rawResponse,omitempty"`
+	IngestionJobID string                 `json:"ingestionJobId"`
+	DataSourceID   string                 `json:"dataSourceId,omitempty"`
+	Status         string                 `json:"status"`
+	StartedAt      time.Time              `json:"startedAt"`
+	UpdatedAt      time.Time              `json:"updatedAt"`
+	Statistics     string                 `json:"statistics"`
+	FailureReasons []string               `json:"failureReasons"`
+	Stats          IngestionJobStatistics `json:"stats"`
+	Failures       []IngestionFailure     `json:"failures,omitempty"`
+	RawResponse    interface{}            `json:"rawResponse,omitempty"`
+}
+
+// IngestionJobStatistics represents the document counts of an ingestion job
+type IngestionJobStatistics struct {
+	DocumentsScanned          int64 `json:"documentsScanned"`
+	MetadataDocumentsScanned  int64 `json:"metadataDocumentsScanned"`
+	MetadataDocumentsModified int64 `json:"metadataDocumentsModified"`
+	NewDocumentsIndexed       int64 `json:"newDocumentsIndexed"`
+	ModifiedDocumentsIndexed  int64 `json:"modifiedDocumentsIndexed"`
+	DocumentsDeleted          int64 `json:"documentsDeleted"`
+	DocumentsFailed           int64 `json:"documentsFailed"`
+}
+
+// IngestionFailure represents a reason an ingestion job failed and the documents it names
+type IngestionFailure struct {
+	Reason    string   `json:"reason"`
+	Documents []string `json:"documents,omitempty"`
+}
+
+// IngestionJobHistory represents a page of ingestion jobs, newest first
+type IngestionJobHistory struct {
+	Jobs      []IngestionJobStatus `json:"jobs"`
+	NextToken string               `json:"nextToken,omitempty"`
 }
 
 // HealthIssue represents an issue with a service
@@ -137,3 +280,124 @@ type MonitorIngestionJobStatus struct {
 	AgentReady         bool        `json:"agentReady"`
 	Message            string      `json:"message"`
 }
+
+// HistoryEntry represents a question a user asked and the answer they received
+type HistoryEntry struct {
+	Question string    `json:"question"`
+	Answer   string    `json:"answer"`
+	Channel  string    `json:"channel"`
+	ThreadTS string    `json:"threadTs"`
+	Success  bool      `json:"success"`
+	AskedAt  time.Time `json:"askedAt"`
+	AliasID  string    `json:"aliasId,omitempty"`
+}
+
+// AnswerOutcome records which agent alias answered a question, how long it took and whether it
+// succeeded or was blocked by a guardrail
+type AnswerOutcome struct {
+	AnswerID   string    `json:"answerId"`
+	AliasID    string    `json:"aliasId"`
+	Channel    string    `json:"channel"`
+	ThreadTS   string    `json:"threadTs"`
+	User       string    `json:"user"`
+	Success    bool      `json:"success"`
+	Blocked    bool      `json:"blocked,omitempty"`
+	LatencyMs  int64     `json:"latencyMs"`
+	AnsweredAt time.Time `json:"answeredAt"`
+}
+
+// AnswerFeedback records a user's thumbs up (1) or thumbs down (-1) on an answer
+type AnswerFeedback struct {
+	AnswerID  string    `json:"answerId"`
+	AliasID   string    `json:"aliasId"`
+	User      string    `json:"user"`
+	Score     int       `json:"score"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AliasReport compares the answers of one agent alias
+type AliasReport struct {
+	AliasID       string  `json:"aliasId"`
+	Role          string  `json:"role"`
+	Answers       int     `json:"answers"`
+	Errors        int     `json:"errors"`
+	ErrorRate     float64 `json:"errorRate"`
+	Blocked       int     `json:"blocked"`
+	AvgLatencyMs  int64   `json:"avgLatencyMs"`
+	P50LatencyMs  int64   `json:"p50LatencyMs"`
+	P95LatencyMs  int64   `json:"p95LatencyMs"`
+	ThumbsUp      int     `json:"thumbsUp"`
+	ThumbsDown    int     `json:"thumbsDown"`
+	FeedbackScore float64 `json:"feedbackScore"`
+}
+
+// ExperimentReport compares the agent aliases that answered questions
+type ExperimentReport struct {
+	ControlAliasID   string        `json:"controlAliasId"`
+	CandidateAliasID string        `json:"candidateAliasId"`
+	CandidatePercent int           `json:"candidatePercent"`
+	Since            time.Time     `json:"since"`
+	Aliases          []AliasReport `json:"aliases"`
+}
+
+// MetadataFilter represents a filter on a knowledge base metadata attribute.
+// Operator is one of "equals", "in" or "startsWith".
+type MetadataFilter struct {
+	Key      string   `json:"key"`
+	Operator string   `json:"operator"`
+	Values   []string `json:"values"`
+}
+
+// RetrievalResult represents a single passage retrieved from the knowledge base
+type RetrievalResult struct {
+	Text     string                 `json:"text"`
+	Score    float64                `json:"score"`
+	Source   string                 `json:"source,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// RetrievalResults represents the passages retrieved for a query
+type RetrievalResults struct {
+	Query   string            `json:"query"`
+	Results []RetrievalResult `json:"results"`
+}
+
+// ActionInvocation represents an action the agent handed back to the bot to run locally.
+// Function is set for function-schema action groups, APIPath and HTTPMethod for OpenAPI ones.
+// InvocationType is RESULT, USER_CONFIRMATION or USER_CONFIRMATION_AND_RESULT.
+type ActionInvocation struct {
+	ActionGroup    string            `json:"actionGroup"`
+	Function       string            `json:"function,omitempty"`
+	APIPath        string            `json:"apiPath,omitempty"`
+	HTTPMethod     string            `json:"httpMethod,omitempty"`
+	Parameters     map[string]string `json:"parameters"`
+	InvocationType string            `json:"invocationType,omitempty"`
+}
+
+// AuditEntry represents a single event recorded in the audit trail
+type AuditEntry struct {
+	Timestamp   time.Time         `json:"timestamp"`
+	Event       string            `json:"event"`
+	SessionID   string            `json:"sessionId,omitempty"`
+	User        string            `json:"user,omitempty"`
+	Channel     string            `json:"channel,omitempty"`
+	ActionGroup string            `json:"actionGroup,omitempty"`
+	Action      string            `json:"action,omitempty"`
+	Parameters  map[string]string `json:"parameters,omitempty"`
+	Result      string            `json:"result,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	DurationMs  int64             `json:"durationMs,omitempty"`
+}
+
+// SyncScheduleStatus represents a scheduled data source sync and its most recent run
+type SyncScheduleStatus struct {
+	Name         string    `json:"name"`
+	DataSourceID string    `json:"dataSourceId"`
+	Cron         string    `json:"cron"`
+	Timezone     string    `json:"timezone"`
+	Paused       bool      `json:"paused"`
+	Running      bool      `json:"running"`
+	NextRun      time.Time `json:"nextRun"`
+	LastRun      time.Time `json:"lastRun"`
+	LastResult   string    `json:"lastResult,omitempty"`
+}
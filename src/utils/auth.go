@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"os"
+	"strings"
+)
+
+// IsMaintainer checks whether a user is listed in RAGBOT_MAINTAINERS, a
+// comma-separated list of Slack user IDs allowed to run administrative actions
+func IsMaintainer(userID string) bool {
+	for _, id := range strings.Split(os.Getenv("RAGBOT_MAINTAINERS"), ",") {
+		if strings.TrimSpace(id) == userID && userID != "" {
+			return true
+		}
+	}
+	return false
+}
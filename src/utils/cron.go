@@ -0,0 +1,232 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed five-field cron expression (minute hour day-of-month month day-of-week)
+type CronSchedule struct {
+	minutes     map[int]bool
+	hours       map[int]bool
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	daysOfWeek  map[int]bool
+
+	// Like classic cron, fields starting with "*" (including steps such as "*/2") are not
+	// restricted: when both day fields are restricted a day matches if either does, and
+	// otherwise it must match both
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+	anyHour       bool
+}
+
+// cronMacros are the supported shorthand schedules
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronMonthNames and cronDayNames are the names accepted in the month and day-of-week fields
+var (
+	cronMonthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	cronDayNames = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+)
+
+// ParseCron parses a cron expression such as "0 2 * * 1-5" or "@daily". Each field accepts
+// "*", numbers, ranges ("1-5"), steps ("*/15", "0-30/10") and comma-separated lists;
+// months and days of the week may also be given by name ("jan", "mon").
+func ParseCron(expression string) (*CronSchedule, error) {
+	expression = strings.TrimSpace(expression)
+	if macro, ok := cronMacros[strings.ToLower(expression)]; ok {
+		expression = macro
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields (minute hour day-of-month month day-of-week)", expression)
+	}
+
+	schedule := &CronSchedule{
+		anyDayOfMonth: unrestrictedCronField(fields[2]),
+		anyDayOfWeek:  unrestrictedCronField(fields[4]),
+		anyHour:       unrestrictedCronField(fields[1]),
+	}
+
+	var err error
+	if schedule.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid minute field: %w", err)
+	}
+	if schedule.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid hour field: %w", err)
+	}
+	if schedule.daysOfMonth, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid day-of-month field: %w", err)
+	}
+	if schedule.months, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return nil, fmt.Errorf("invalid month field: %w", err)
+	}
+	if schedule.daysOfWeek, err = parseCronField(fields[4], 0, 7, cronDayNames); err != nil {
+		return nil, fmt.Errorf("invalid day-of-week field: %w", err)
+	}
+
+	// Both 0 and 7 mean Sunday
+	if schedule.daysOfWeek[7] {
+		schedule.daysOfWeek[0] = true
+	}
+
+	return schedule, nil
+}
+
+// unrestrictedCronField checks whether a field starts with "*" or "?", as classic cron does
+func unrestrictedCronField(field string) bool {
+	return strings.HasPrefix(field, "*") || strings.HasPrefix(field, "?")
+}
+
+// parseCronField parses one field of a cron expression into the set of values it matches
+func parseCronField(field string, min, max int, names map[string]int) (map[int]bool, error) {
+	values := map[int]bool{}
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			parsed, err := strconv.Atoi(stepPart)
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = parsed
+		}
+
+		start, end := min, max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			low, high, _ := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = parseCronValue(low, names); err != nil {
+				return nil, err
+			}
+			if end, err = parseCronValue(high, names); err != nil {
+				return nil, err
+			}
+		default:
+			value, err := parseCronValue(rangePart, names)
+			if err != nil {
+				return nil, err
+			}
+			start = value
+			// "5/15" means every 15 starting at 5
+			if !hasStep {
+				end = value
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for value := start; value <= end; value += step {
+			values[value] = true
+		}
+	}
+
+	return values, nil
+}
+
+// parseCronValue parses a number or a name in a cron field
+func parseCronValue(value string, names map[string]int) (int, error) {
+	if number, ok := names[strings.ToLower(value)]; ok {
+		return number, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	return number, nil
+}
+
+// Next returns the first time after t that matches the schedule, in t's location.
+// It returns the zero time if nothing matches within five years (e.g. "0 0 31 2 *").
+//
+// Schedules with a wildcard hour follow the clock across daylight saving changes, and run
+// in both copies of a repeated hour. Schedules at fixed hours run once per wall-clock time:
+// a time repeated when clocks go back runs only the first time, and a time skipped when
+// clocks go forward runs that much later (e.g. 02:30 runs at 03:30).
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	if c.anyHour {
+		return c.next(t)
+	}
+
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	for {
+		wall = c.next(wall)
+		if wall.IsZero() {
+			return wall
+		}
+
+		next := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), 0, 0, t.Location())
+		if next.Hour() != wall.Hour() || next.Minute() != wall.Minute() {
+			// The time was skipped when clocks went forward, so read it with the offset in
+			// effect before the change
+			_, offset := next.Add(-12 * time.Hour).Zone()
+			next = time.Unix(wall.Unix()-int64(offset), 0).In(t.Location())
+		}
+		if next.After(t) {
+			return next
+		}
+	}
+}
+
+// next returns the first time after t that matches the schedule, stepping through t's location
+func (c *CronSchedule) next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for next.Before(limit) {
+		if !c.months[int(next.Month())] {
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if !c.matchesDay(next) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if !c.hours[next.Hour()] {
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+			continue
+		}
+		if !c.minutes[next.Minute()] {
+			next = next.Add(time.Minute)
+			continue
+		}
+		return next
+	}
+
+	return time.Time{}
+}
+
+// matchesDay checks the day-of-month and day-of-week fields for a date
+func (c *CronSchedule) matchesDay(t time.Time) bool {
+	dayOfMonth := c.daysOfMonth[t.Day()]
+	dayOfWeek := c.daysOfWeek[int(t.Weekday())]
+
+	if c.anyDayOfMonth || c.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
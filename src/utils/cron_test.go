@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseCronField(t *testing.T) {
+	tests := []struct {
+		field    string
+		min, max int
+		names    map[string]int
+		want     []int
+	}{
+		{"*", 0, 5, nil, []int{0, 1, 2, 3, 4, 5}},
+		{"?", 1, 3, nil, []int{1, 2, 3}},
+		{"4", 0, 59, nil, []int{4}},
+		{"1-5", 0, 59, nil, []int{1, 2, 3, 4, 5}},
+		{"*/15", 0, 59, nil, []int{0, 15, 30, 45}},
+		{"0-30/10", 0, 59, nil, []int{0, 10, 20, 30}},
+		{"5/15", 0, 59, nil, []int{5, 20, 35, 50}},
+		{"*/10", 1, 31, nil, []int{1, 11, 21, 31}},
+		{"1,3,5-6", 0, 23, nil, []int{1, 3, 5, 6}},
+		{"1-3,2-4", 0, 23, nil, []int{1, 2, 3, 4}},
+		{"jan-mar", 1, 12, cronMonthNames, []int{1, 2, 3}},
+		{"JUL,dec", 1, 12, cronMonthNames, []int{7, 12}},
+		{"mon-fri", 0, 7, cronDayNames, []int{1, 2, 3, 4, 5}},
+		{"sat,sun", 0, 7, cronDayNames, []int{0, 6}},
+	}
+
+	for _, test := range tests {
+		values, err := parseCronField(test.field, test.min, test.max, test.names)
+		if err != nil {
+			t.Errorf("parseCronField(%q) error = %v", test.field, err)
+			continue
+		}
+
+		var got []int
+		for value := range values {
+			got = append(got, value)
+		}
+		sort.Ints(got)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseCronField(%q) = %v, want %v", test.field, got, test.want)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"0 2 * *",
+		"0 2 * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"0 0 0 * *",
+		"0 0 32 * *",
+		"0 0 * 13 *",
+		"0 0 * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"x * * * *",
+		"0 0 * foo *",
+		"@sometimes",
+	} {
+		if _, err := ParseCron(expression); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", expression)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		from       string
+		want       string
+	}{
+		{"every 15 minutes", "*/15 * * * *", "2024-01-01T10:07:00Z", "2024-01-01T10:15:00Z"},
+		{"on a matching minute", "*/15 * * * *", "2024-01-01T10:15:00Z", "2024-01-01T10:30:00Z"},
+		{"seconds are ignored", "* * * * *", "2024-01-01T10:15:59Z", "2024-01-01T10:16:00Z"},
+		{"hourly", "@hourly", "2024-01-01T10:00:00Z", "2024-01-01T11:00:00Z"},
+		{"list of hours", "0 6,18 * * *", "2024-01-01T07:00:00Z", "2024-01-01T18:00:00Z"},
+		{"weekdays skip the weekend", "0 2 * * 1-5", "2024-01-05T03:00:00Z", "2024-01-08T02:00:00Z"},
+		{"weekly on sunday", "@weekly", "2024-01-03T12:00:00Z", "2024-01-07T00:00:00Z"},
+		{"sunday as 7", "0 0 * * 7", "2024-01-03T12:00:00Z", "2024-01-07T00:00:00Z"},
+		{"month and day names", "30 9 * jan,jul mon", "2024-02-01T00:00:00Z", "2024-07-01T09:30:00Z"},
+		{"next month", "0 0 1 * *", "2024-01-31T12:00:00Z", "2024-02-01T00:00:00Z"},
+		{"31st skips shorter months", "0 0 31 * *", "2024-04-15T00:00:00Z", "2024-05-31T00:00:00Z"},
+		{"29 february in a leap year", "0 12 29 2 *", "2023-03-01T00:00:00Z", "2024-02-29T12:00:00Z"},
+		{"end of the year", "59 23 31 12 *", "2024-12-31T23:59:00Z", "2025-12-31T23:59:00Z"},
+		{"new year", "@yearly", "2024-12-31T23:59:00Z", "2025-01-01T00:00:00Z"},
+		{"never", "0 0 31 2 *", "2024-01-01T00:00:00Z", ""},
+
+		// When both day fields are restricted, either may match
+		{"13th or friday, friday first", "0 0 13 * 5", "2024-09-01T00:00:00Z", "2024-09-06T00:00:00Z"},
+		{"13th or friday, 13th first", "0 0 13 * 5", "2024-09-07T00:00:00Z", "2024-09-13T00:00:00Z"},
+		{"1st or monday", "0 0 1 * mon", "2024-08-27T00:00:00Z", "2024-09-01T00:00:00Z"},
+
+		// A day field starting with "*" is not restricted, so both must match
+		{"odd days that are mondays", "0 0 */2 * 1", "2024-09-01T00:00:00Z", "2024-09-09T00:00:00Z"},
+		{"first days that are odd weekdays", "0 0 1-7 * */2", "2024-09-01T00:00:00Z", "2024-09-03T00:00:00Z"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := ParseCron(test.expression)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) error = %v", test.expression, err)
+			}
+
+			from, _ := time.Parse(time.RFC3339, test.from)
+			got := schedule.Next(from)
+			if test.want == "" {
+				if !got.IsZero() {
+					t.Errorf("Next(%s) = %s, want none", test.from, got)
+				}
+				return
+			}
+			want, _ := time.Parse(time.RFC3339, test.want)
+			if !got.Equal(want) {
+				t.Errorf("Next(%s) = %s, want %s", test.from, got.UTC().Format(time.RFC3339), test.want)
+			}
+		})
+	}
+}
+
+func TestCronNextDaylightSaving(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// In 2024 New York clocks went from 02:00 EST to 03:00 EDT on 10 March and from
+	// 02:00 EDT back to 01:00 EST on 3 November. Times are given in UTC.
+	tests := []struct {
+		name       string
+		expression string
+		from       string
+		want       string
+	}{
+		{"fixed time before the gap", "30 1 * * *", "2024-03-10T05:00:00Z", "2024-03-10T06:30:00Z"},
+		{"fixed time in the gap runs an hour later", "30 2 * * *", "2024-03-10T05:00:00Z", "2024-03-10T07:30:00Z"},
+		{"fixed time after a skipped run", "30 2 * * *", "2024-03-10T07:30:00Z", "2024-03-11T06:30:00Z"},
+		{"fixed time after the gap", "0 3 * * *", "2024-03-10T05:00:00Z", "2024-03-10T07:00:00Z"},
+		{"wildcard hour across the gap", "*/30 * * * *", "2024-03-10T06:45:00Z", "2024-03-10T07:00:00Z"},
+		{"daily across the gap", "@daily", "2024-03-10T05:00:00Z", "2024-03-11T04:00:00Z"},
+		{"repeated time runs once", "30 1 * * *", "2024-11-03T04:00:00Z", "2024-11-03T05:30:00Z"},
+		{"repeated time is not run again", "30 1 * * *", "2024-11-03T05:30:00Z", "2024-11-04T06:30:00Z"},
+		{"during the repeated hour", "45 1 * * *", "2024-11-03T06:10:00Z", "2024-11-04T06:45:00Z"},
+		{"wildcard hour runs in both copies", "*/30 * * * *", "2024-11-03T05:45:00Z", "2024-11-03T06:00:00Z"},
+		{"fixed time after the overlap", "0 2 * * *", "2024-11-03T05:30:00Z", "2024-11-03T07:00:00Z"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := ParseCron(test.expression)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) error = %v", test.expression, err)
+			}
+
+			from, _ := time.Parse(time.RFC3339, test.from)
+			want, _ := time.Parse(time.RFC3339, test.want)
+			got := schedule.Next(from.In(newYork))
+			if !got.Equal(want) {
+				t.Errorf("Next(%s) = %s, want %s", test.from, got.UTC().Format(time.RFC3339), test.want)
+			}
+			if got.Location() != newYork {
+				t.Errorf("Next(%s) is in %s, want America/New_York", test.from, got.Location())
+			}
+		})
+	}
+}
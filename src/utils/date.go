@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -15,3 +16,14 @@ func FormatDate(date time.Time) string {
 	// Go equivalent of 'en-US' locale with year, month, day, hour, minute
 	return date.Format("January 2, 2006 3:04 PM")
 }
+
+// SlackDate formats a time.Time with Slack's <!date> syntax, which Slack renders in the
+// viewer's timezone. Clients that cannot render it show the UTC time instead.
+// If date is zero, returns "No date provided"
+func SlackDate(date time.Time) string {
+	if date.IsZero() {
+		return "No date provided"
+	}
+
+	return fmt.Sprintf("<!date^%d^{date_short_pretty} at {time}|%s UTC>", date.Unix(), FormatDate(date.UTC()))
+}
@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"slack-rag-server/src/types"
+)
+
+// defaultWakePhrases are used when RAGBOT_WAKE_PHRASES is not set
+var defaultWakePhrases = []string{"hey ragbot"}
+
+// mentionPattern matches any Slack user mention such as <@U123> or <@U123|name>
+var mentionPattern = regexp.MustCompile(`<@([A-Z0-9]+)(?:\|[^>]*)?>`)
+
+// wordPattern matches a single whitespace-separated word
+var wordPattern = regexp.MustCompile(`\S+`)
+
+// codePattern matches code fences (up to the closing fence or the end of the text) and inline
+// code spans, where flags are left alone
+var codePattern = regexp.MustCompile("(?s)```.*?(?:```|$)|`[^`\n]*`")
+
+// wakeSeparator matches the whitespace and punctuation allowed between and after wake phrase words
+const wakeSeparator = `[\s\p{P}]`
+
+// wakePatterns are the compiled wake phrases, built on first use once the environment is loaded
+var (
+	wakePatternsOnce sync.Once
+	wakePatterns     []*regexp.Regexp
+)
+
+// messageFlags lists the inline flags understood in questions and whether they take a value
+var messageFlags = map[string]bool{
+	"traceback":     false,
+	"agent":         true,
+	"private":       false,
+	"new-session":   false,
+	"retrieve-only": false,
+}
+
+// StripBotMentions removes every mention of the bot from the text. If the bot's user ID
+// is unknown, only a leading mention is removed.
+func StripBotMentions(text, botUserID string) string {
+	if botUserID == "" {
+		trimmed := strings.TrimSpace(text)
+		if loc := mentionPattern.FindStringIndex(trimmed); loc != nil && loc[0] == 0 {
+			return strings.TrimSpace(trimmed[loc[1]:])
+		}
+		return trimmed
+	}
+
+	// Remove each mention with the spaces after it, keeping the line breaks and indentation
+	// of the rest of the text
+	var stripped strings.Builder
+	last := 0
+	for _, loc := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		if text[loc[2]:loc[3]] != botUserID {
+			continue
+		}
+		stripped.WriteString(text[last:loc[0]])
+		last = loc[1] + len(text[loc[1]:]) - len(strings.TrimLeft(text[loc[1]:], " \t"))
+	}
+	stripped.WriteString(text[last:])
+	return strings.TrimSpace(stripped.String())
+}
+
+// WakePhrases returns the configured wake phrases from RAGBOT_WAKE_PHRASES
+// (comma-separated), or the defaults
+func WakePhrases() []string {
+	value := os.Getenv("RAGBOT_WAKE_PHRASES")
+	if value == "" {
+		return defaultWakePhrases
+	}
+
+	var phrases []string
+	for _, phrase := range strings.Split(value, ",") {
+		if phrase = strings.TrimSpace(phrase); phrase != "" {
+			phrases = append(phrases, phrase)
+		}
+	}
+	return phrases
+}
+
+// compileWakePhrases builds a pattern for each wake phrase that matches it at the start of
+// a message, ignoring case and punctuation
+func compileWakePhrases(phrases []string) []*regexp.Regexp {
+	var patterns []*regexp.Regexp
+	for _, phrase := range phrases {
+		words := strings.Fields(phrase)
+		if len(words) == 0 {
+			continue
+		}
+
+		quoted := make([]string, len(words))
+		for i, word := range words {
+			quoted[i] = regexp.QuoteMeta(word)
+		}
+
+		patterns = append(patterns, regexp.MustCompile(`(?i)^\s*`+strings.Join(quoted, wakeSeparator+`+`)+`(?:`+wakeSeparator+`+|$)`))
+	}
+	return patterns
+}
+
+// MatchWakePhrase checks whether the text starts with one of the wake phrases, ignoring case
+// and punctuation (e.g. "Hey, Ragbot!"), and returns the text that follows it
+func MatchWakePhrase(text string) (bool, string) {
+	wakePatternsOnce.Do(func() {
+		wakePatterns = compileWakePhrases(WakePhrases())
+	})
+
+	for _, pattern := range wakePatterns {
+		if loc := pattern.FindStringIndex(text); loc != nil {
+			return true, strings.TrimSpace(text[loc[1]:])
+		}
+	}
+	return false, text
+}
+
+// ParseMessage extracts inline flags (--traceback, --agent=<name>, --private, --new-session,
+// --retrieve-only) from anywhere in the text outside code spans and fences, and returns them
+// along with the remaining question text, which keeps its line breaks and indentation
+func ParseMessage(text string) types.ParsedMessage {
+	parsed := types.ParsedMessage{}
+	code := codePattern.FindAllStringIndex(text, -1)
+
+	var remaining strings.Builder
+	last := 0
+	for _, loc := range wordPattern.FindAllStringIndex(text, -1) {
+		if overlapsAny(loc, code) {
+			continue
+		}
+		name, value, ok := parseFlag(text[loc[0]:loc[1]])
+		if !ok {
+			continue
+		}
+
+		switch name {
+		case "traceback":
+			parsed.Traceback = true
+		case "agent":
+			parsed.Agent = value
+		case "private":
+			parsed.Private = true
+		case "new-session":
+			parsed.NewSession = true
+		case "retrieve-only":
+			parsed.RetrieveOnly = true
+		}
+
+		// Drop the flag with the spaces after it, or before it when it ends a line
+		start, end := loc[0], loc[1]
+		end += len(text[end:]) - len(strings.TrimLeft(text[end:], " \t"))
+		if end == len(text) || text[end] == '\n' || text[end] == '\r' {
+			start = last + len(strings.TrimRight(text[last:start], " \t"))
+		}
+		remaining.WriteString(text[last:start])
+		last = end
+	}
+	remaining.WriteString(text[last:])
+
+	parsed.Text = strings.TrimSpace(remaining.String())
+	return parsed
+}
+
+// overlapsAny checks whether a range of the text overlaps any of the given ranges
+func overlapsAny(loc []int, ranges [][]int) bool {
+	for _, r := range ranges {
+		if loc[0] < r[1] && r[0] < loc[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFlag parses a single --flag or --flag=value word, returning false for unknown flags
+func parseFlag(word string) (string, string, bool) {
+	if !strings.HasPrefix(word, "--") {
+		return "", "", false
+	}
+
+	name, value, hasValue := strings.Cut(strings.ToLower(word[2:]), "=")
+	takesValue, known := messageFlags[name]
+	if !known || takesValue != hasValue || (takesValue && value == "") {
+		return "", "", false
+	}
+
+	// Keep the original case of the value
+	if hasValue {
+		value = word[len(word)-len(value):]
+	}
+	return name, value, true
+}
+
+// ParseMetadataFilter parses a filter expression: "key=value" (equals), "key=a|b" (in)
+// or "key^=prefix" (startsWith)
+func ParseMetadataFilter(expression string) (types.MetadataFilter, error) {
+	if key, value, ok := strings.Cut(expression, "^="); ok && key != "" && value != "" {
+		return types.MetadataFilter{Key: key, Operator: "startsWith", Values: []string{value}}, nil
+	}
+
+	key, value, ok := strings.Cut(expression, "=")
+	if !ok || key == "" || value == "" {
+		return types.MetadataFilter{}, fmt.Errorf("invalid filter %q, expected key=value, key=a|b or key^=prefix", expression)
+	}
+
+	if strings.Contains(value, "|") {
+		return types.MetadataFilter{Key: key, Operator: "in", Values: strings.Split(value, "|")}, nil
+	}
+	return types.MetadataFilter{Key: key, Operator: "equals", Values: []string{value}}, nil
+}
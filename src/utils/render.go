@@ -0,0 +1,242 @@
+package utils
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+)
+
+// maxSectionTextLength is Slack's limit on the text of a single section block
+const maxSectionTextLength = 3000
+
+// maxSectionsPerMessage is the number of section blocks posted per message. Slack allows
+// 50 blocks, but messages are kept small so each stays well under the message size limit.
+const maxSectionsPerMessage = 10
+
+// defaultUploadThreshold is the length above which a response is uploaded as a file when
+// RAGBOT_UPLOAD_THRESHOLD is not set
+const defaultUploadThreshold = 15000
+
+// uploadPreviewLength is the length of the preview posted alongside an uploaded answer
+const uploadPreviewLength = 1500
+
+var (
+	headingPattern    = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.*?)\s*#*\s*$`)
+	bulletPattern     = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+	linkPattern       = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^)\s]+)\)`)
+	boldStarPattern   = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	boldUnderPattern  = regexp.MustCompile(`__(.+?)__`)
+	italicPattern     = regexp.MustCompile(`(^|[^*\w])\*([^*\s](?:[^*]*[^*\s])?)\*`)
+	strikePattern     = regexp.MustCompile(`~~(.+?)~~`)
+	tableRulePattern  = regexp.MustCompile(`^\s*\|?\s*:?-{2,}:?\s*(\|\s*:?-{2,}:?\s*)*\|?\s*$`)
+	boldPlaceholder   = "\x01"
+	codeFenceMarker   = "```"
+	codeFenceReopener = codeFenceMarker + "\n"
+)
+
+// UploadThreshold returns the response length above which answers are uploaded as a file
+func UploadThreshold() int {
+	if value := os.Getenv("RAGBOT_UPLOAD_THRESHOLD"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return defaultUploadThreshold
+}
+
+// MarkdownToMrkdwn converts the markdown produced by agents into Slack mrkdwn: headings
+// become bold lines, bold/italic/strike markers and links are rewritten, bullets are
+// normalized, code fences lose their language tag and tables are rendered as aligned
+// preformatted text
+func MarkdownToMrkdwn(markdown string) string {
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+	var output []string
+	inCode := false
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, codeFenceMarker) {
+			inCode = !inCode
+			output = append(output, codeFenceMarker)
+			continue
+		}
+		if inCode {
+			output = append(output, line)
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "|") {
+			var table []string
+			for i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|") {
+				table = append(table, lines[i])
+				i++
+			}
+			i--
+			output = append(output, renderTable(table))
+			continue
+		}
+
+		if match := headingPattern.FindStringSubmatch(line); match != nil {
+			heading := strings.NewReplacer("**", "", "__", "").Replace(match[1])
+			output = append(output, "*"+heading+"*")
+			continue
+		}
+
+		line = bulletPattern.ReplaceAllString(line, "${1}• ")
+		output = append(output, convertInline(line))
+	}
+
+	return strings.Join(output, "\n")
+}
+
+// convertInline rewrites inline markdown outside of `code` spans
+func convertInline(line string) string {
+	segments := strings.Split(line, "`")
+	for i := 0; i < len(segments); i += 2 {
+		segment := linkPattern.ReplaceAllString(segments[i], "<$2|$1>")
+		segment = boldStarPattern.ReplaceAllString(segment, boldPlaceholder+"$1"+boldPlaceholder)
+		segment = boldUnderPattern.ReplaceAllString(segment, boldPlaceholder+"$1"+boldPlaceholder)
+		segment = italicPattern.ReplaceAllString(segment, "${1}_${2}_")
+		segment = strikePattern.ReplaceAllString(segment, "~$1~")
+		segments[i] = strings.ReplaceAll(segment, boldPlaceholder, "*")
+	}
+	return strings.Join(segments, "`")
+}
+
+// renderTable renders markdown table rows as an aligned preformatted block
+func renderTable(rows []string) string {
+	var cells [][]string
+	var widths []int
+
+	for _, row := range rows {
+		if tableRulePattern.MatchString(row) {
+			continue
+		}
+
+		row = strings.TrimSpace(row)
+		row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+		columns := strings.Split(row, "|")
+		for i, column := range columns {
+			columns[i] = strings.TrimSpace(column)
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if length := utf8.RuneCountInString(columns[i]); length > widths[i] {
+				widths[i] = length
+			}
+		}
+		cells = append(cells, columns)
+	}
+
+	lines := []string{codeFenceMarker}
+	for _, columns := range cells {
+		padded := make([]string, len(columns))
+		for i, column := range columns {
+			padded[i] = column + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(column))
+		}
+		lines = append(lines, strings.TrimRight(strings.Join(padded, " | "), " "))
+	}
+	lines = append(lines, codeFenceMarker)
+
+	return strings.Join(lines, "\n")
+}
+
+// SplitText splits text into chunks of at most limit bytes, preferring paragraph, line
+// and word boundaries. Code fences that span a split are closed and reopened so every
+// chunk renders correctly on its own.
+func SplitText(text string, limit int) []string {
+	var chunks []string
+	// Leave room for closing and reopening a code fence
+	searchLimit := limit - len(codeFenceReopener) - len("\n"+codeFenceMarker)
+
+	for len(text) > limit {
+		cut := findCut(text, searchLimit)
+		chunk := strings.TrimRight(text[:cut], " \n")
+		rest := strings.TrimLeft(text[cut:], " \n")
+
+		if strings.Count(chunk, codeFenceMarker)%2 == 1 {
+			chunk += "\n" + codeFenceMarker
+			rest = codeFenceReopener + rest
+		}
+
+		chunks = append(chunks, chunk)
+		text = rest
+	}
+
+	if strings.TrimSpace(text) != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
+
+// findCut finds the best place to split text at or before limit bytes
+func findCut(text string, limit int) int {
+	window := text[:limit]
+	for _, separator := range []string{"\n\n", "\n", " "} {
+		if index := strings.LastIndex(window, separator); index > limit/2 {
+			return index + len(separator)
+		}
+	}
+
+	// No good boundary, so cut on a rune boundary
+	return runeCut(text, limit)
+}
+
+// runeCut returns the largest index at or before limit that doesn't split a multi-byte character
+func runeCut(text string, limit int) int {
+	if limit >= len(text) {
+		return len(text)
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return cut
+}
+
+// TruncateText shortens text longer than limit bytes to at most limit bytes and an ellipsis,
+// without splitting a multi-byte character
+func TruncateText(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	return text[:runeCut(text, limit)] + "…"
+}
+
+// RenderMessages converts markdown into one or more sets of Slack blocks, each small
+// enough to post as a single message
+func RenderMessages(markdown string) [][]slack.Block {
+	var messages [][]slack.Block
+	var blocks []slack.Block
+
+	for _, chunk := range SplitText(MarkdownToMrkdwn(markdown), maxSectionTextLength) {
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, chunk, false, false),
+			nil,
+			nil,
+		))
+		if len(blocks) == maxSectionsPerMessage {
+			messages = append(messages, blocks)
+			blocks = nil
+		}
+	}
+
+	if len(blocks) > 0 {
+		messages = append(messages, blocks)
+	}
+	return messages
+}
+
+// previewText returns the beginning of a long text, cut at a line boundary
+func previewText(text string) string {
+	if len(text) <= uploadPreviewLength {
+		return text
+	}
+	return SplitText(text, uploadPreviewLength)[0] + "\n…"
+}
@@ -6,16 +6,6 @@ import (
 	"github.com/slack-go/slack"
 )
 
-// HandleTracebackFlag extracts the --traceback flag from text and returns
-// whether traceback should be included and the cleaned input text
-func HandleTracebackFlag(text string) (bool, string) {
-	words := strings.Fields(text)
-	if len(words) > 0 && words[0] == "--traceback" {
-		return true, strings.Join(words[1:], " ")
-	}
-	return false, text
-}
-
 // AddReaction adds a reaction to a message
 func AddReaction(api *slack.Client, channel, timestamp, name string) error {
 	err := api.AddReaction(name, slack.ItemRef{
@@ -31,24 +21,148 @@ func AddReaction(api *slack.Client, channel, timestamp, name string) error {
 	return nil
 }
 
-// SendSlackMessage sends a message to a Slack channel
-func SendSlackMessage(api *slack.Client, channel, text, threadTS string) error {
+// SendSlackMessage sends a message to a Slack channel. Markdown is converted to Slack
+// mrkdwn, long text is split across several messages and text beyond the upload
+// threshold is uploaded as a file with a short preview. Any extra blocks, such as buttons,
+// are added to the last message.
+func SendSlackMessage(api *slack.Client, channel, text, threadTS string, extra ...slack.Block) error {
+	if len(text) > UploadThreshold() {
+		return uploadLongMessage(api, channel, text, threadTS, extra...)
+	}
+
+	for _, blocks := range withExtraBlocks(RenderMessages(text), extra) {
+		_, _, err := api.PostMessage(
+			channel,
+			slack.MsgOptionText(fallbackText(blocks), false),
+			slack.MsgOptionTS(threadTS),
+			slack.MsgOptionBlocks(blocks...),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SendEphemeralMessage sends a message in a channel or thread that only the given user can see.
+// Ephemeral messages cannot carry files, so long text is always split across messages.
+func SendEphemeralMessage(api *slack.Client, channel, user, text, threadTS string, extra ...slack.Block) error {
+	for _, blocks := range withExtraBlocks(RenderMessages(text), extra) {
+		_, err := api.PostEphemeral(
+			channel,
+			user,
+			slack.MsgOptionText(fallbackText(blocks), false),
+			slack.MsgOptionTS(threadTS),
+			slack.MsgOptionBlocks(blocks...),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SendSlackBlocks sends a Block Kit message to a Slack channel, with text used for notifications
+func SendSlackBlocks(api *slack.Client, channel, text, threadTS string, blocks []slack.Block) error {
 	_, _, err := api.PostMessage(
 		channel,
 		slack.MsgOptionText(text, false),
 		slack.MsgOptionTS(threadTS),
-		slack.MsgOptionBlocks(
-			slack.NewSectionBlock(
-				slack.NewTextBlockObject(slack.MarkdownType, text, false, false),
-				nil,
-				nil,
-			),
-		),
+		slack.MsgOptionBlocks(blocks...),
 	)
 
 	return err
 }
 
+// SendEphemeralBlocks sends a Block Kit message that only the given user can see
+func SendEphemeralBlocks(api *slack.Client, channel, user, text, threadTS string, blocks []slack.Block) error {
+	_, err := api.PostEphemeral(
+		channel,
+		user,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionTS(threadTS),
+		slack.MsgOptionBlocks(blocks...),
+	)
+
+	return err
+}
+
+// uploadLongMessage posts a preview of the text and uploads the full text as a markdown file
+func uploadLongMessage(api *slack.Client, channel, text, threadTS string, extra ...slack.Block) error {
+	preview := previewText(text) + "\n\n_The full answer is too long for Slack and is attached as a file._"
+	for _, blocks := range withExtraBlocks(RenderMessages(preview), extra) {
+		_, _, err := api.PostMessage(
+			channel,
+			slack.MsgOptionText(fallbackText(blocks), false),
+			slack.MsgOptionTS(threadTS),
+			slack.MsgOptionBlocks(blocks...),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := api.UploadFileV2(slack.UploadFileV2Parameters{
+		Content:         text,
+		FileSize:        len(text),
+		Filename:        "ragbot-answer.md",
+		Title:           "Ragbot answer",
+		Channel:         channel,
+		ThreadTimestamp: threadTS,
+	})
+	return err
+}
+
+// withExtraBlocks adds blocks to the last of a set of messages
+func withExtraBlocks(messages [][]slack.Block, extra []slack.Block) [][]slack.Block {
+	if len(extra) == 0 {
+		return messages
+	}
+	if len(messages) == 0 {
+		return [][]slack.Block{extra}
+	}
+
+	last := len(messages) - 1
+	messages[last] = append(messages[last], extra...)
+	return messages
+}
+
+// fallbackText returns the notification text for a set of blocks
+func fallbackText(blocks []slack.Block) string {
+	if len(blocks) == 0 {
+		return ""
+	}
+	if section, ok := blocks[0].(*slack.SectionBlock); ok && section.Text != nil {
+		return section.Text.Text
+	}
+	return ""
+}
+
+// GetThreadMessages fetches every message in a thread, following pagination cursors
+func GetThreadMessages(api *slack.Client, channel, threadTS string) ([]slack.Message, error) {
+	var messages []slack.Message
+	cursor := ""
+
+	for {
+		batch, hasMore, nextCursor, err := api.GetConversationReplies(&slack.GetConversationRepliesParameters{
+			ChannelID: channel,
+			Timestamp: threadTS,
+			Cursor:    cursor,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, batch...)
+		if !hasMore || nextCursor == "" {
+			return messages, nil
+		}
+		cursor = nextCursor
+	}
+}
+
 // HandleError handles an error by adding an X reaction and sending an error message
 func HandleError(api *slack.Client, err error, channel, timestamp, threadTS string, messageID string) error {
 	LogError(err, "")